@@ -103,6 +103,8 @@ export default function CreateCapsule() {
                 const { data } = await getPresignedUrl({
                     content_type: currentTab,
                     file_name: selectedFile.name,
+                    file_type: selectedFile.type,
+                    file_size: selectedFile.size,
                 });
                 const uploadResponse = await fetch(data.presigned_url, {
                     method: "PUT",
@@ -115,11 +117,15 @@ export default function CreateCapsule() {
                 if (!uploadResponse.ok)
                     throw new Error("Failed to upload file");
 
+                // The bucket is private, previews use the local file.
+                const previewUrl = URL.createObjectURL(selectedFile);
+
                 if (currentTab === "image") {
                     newItem = {
                         type: "image" as ContentType,
                         content: {
-                            url: data.final_url,
+                            object_key: data.object_key,
+                            url: previewUrl,
                             caption: caption,
                             alt_text: altText,
                         } as ImageContent,
@@ -128,7 +134,8 @@ export default function CreateCapsule() {
                     newItem = {
                         type: "video" as ContentType,
                         content: {
-                            url: data.final_url,
+                            object_key: data.object_key,
+                            url: previewUrl,
                             caption: caption,
                         } as VideoContent,
                     };
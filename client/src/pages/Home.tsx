@@ -252,7 +252,7 @@ const Home = (): React.ReactElement => {
                 <div className="space-y-4">
                     {capsules.map((capsule) => (
                         <Card
-                            key={capsule._id}
+                            key={capsule.id}
                             className={`
                                     border-l-4 transition-all shadow-sm hover:shadow-md pb-4
                                     ${
@@ -410,7 +410,7 @@ const Home = (): React.ReactElement => {
                                                 <AlertDialogAction
                                                     onClick={() =>
                                                         deleteCapsuleMutation(
-                                                            capsule._id
+                                                            capsule.id
                                                         )
                                                     }
                                                     className="bg-red-600 hover:bg-red-700"
@@ -423,7 +423,7 @@ const Home = (): React.ReactElement => {
                                 )}
                                 {capsule.is_opened && (
                                     <Link
-                                        to={`/capsule/${capsule._id}`}
+                                        to={`/capsule/${capsule.id}`}
                                         className="w-full sm:w-auto"
                                     >
                                         <Button
@@ -94,13 +94,26 @@ export default function OpenedCapsule() {
                                     <div key={index} className="last:pb-0">
                                         {item.type === "message" && (
                                             <div className="my-4">
-                                                <p className="text-gray-800 leading-relaxed">
-                                                    {
-                                                        (
-                                                            item.content as MessageContent
-                                                        ).text
-                                                    }
-                                                </p>
+                                                {(item.content as MessageContent)
+                                                    .html ? (
+                                                    // The server only sends sanitized HTML.
+                                                    <div
+                                                        className="text-gray-800 leading-relaxed"
+                                                        dangerouslySetInnerHTML={{
+                                                            __html: (
+                                                                item.content as MessageContent
+                                                            ).html!,
+                                                        }}
+                                                    />
+                                                ) : (
+                                                    <p className="text-gray-800 leading-relaxed">
+                                                        {
+                                                            (
+                                                                item.content as MessageContent
+                                                            ).text
+                                                        }
+                                                    </p>
+                                                )}
                                             </div>
                                         )}
 
@@ -44,13 +44,22 @@ export const createCapsule = async (newCapsule: CreateCapsuleType) => {
 export const getPresignedUrl = async ({
     content_type,
     file_name,
+    file_type,
+    file_size,
 }: {
     content_type: ContentType;
     file_name: string;
+    file_type: string;
+    file_size: number;
 }) => {
     const { data } = await apiClient.post<
         APIResponseType<PresignedAWSResponse>
-    >("/api/uploader/presigned-url", { content_type, file_name });
+    >("/api/uploader/presigned-url", {
+        content_type,
+        file_name,
+        file_type,
+        file_size,
+    });
     return data;
 };
 
@@ -1,5 +1,8 @@
+export type CapsuleStatus = "draft" | "sealed" | "opened" | "archived";
+
 export interface Capsule {
-    _id: string;
+    id: string;
+    status: CapsuleStatus;
     creator: string;
     title: string;
     description: string;
@@ -12,33 +15,114 @@ export interface Capsule {
 
 export type CreateCapsuleType = Omit<
     Capsule,
-    "_id" | "is_opened" | "created_at" | "creator"
+    "id" | "status" | "is_opened" | "created_at" | "creator"
 >;
 
-export type ContentType = "message" | "image" | "video";
+export type ContentType =
+    | "message"
+    | "image"
+    | "video"
+    | "audio"
+    | "file"
+    | "prediction"
+    | "goals"
+    | "link";
 
 export interface ContentItem {
     type: ContentType;
     content: ContentItemDetail;
 }
 
-export type ContentItemDetail = MessageContent | ImageContent | VideoContent;
+export type ContentItemDetail =
+    | MessageContent
+    | ImageContent
+    | VideoContent
+    | AudioContent
+    | FileContent
+    | PredictionContent
+    | GoalsContent
+    | LinkContent;
+
+export type MessageFormat = "plain" | "markdown";
 
 export interface MessageContent {
     text: string;
+    format?: MessageFormat;
+    html?: string;
 }
 
 export interface ImageContent {
+    object_key: string;
     url: string;
     caption: string;
     alt_text: string;
 }
 
 export interface VideoContent {
+    object_key: string;
     url: string;
     caption: string;
 }
 
+export interface AudioContent {
+    object_key: string;
+    url: string;
+    duration_seconds: number;
+    transcript: string;
+}
+
+export interface FileContent {
+    name: string;
+    size: number;
+    mime_type: string;
+    object_key: string;
+    url: string;
+}
+
+export interface PredictionAnswer {
+    participant_email: string;
+    answer: string;
+    answered_at: Date;
+    came_true: boolean | null;
+}
+
+export interface PredictionContent {
+    id: string;
+    question: string;
+    options?: string[];
+    answers: PredictionAnswer[];
+}
+
+export type GoalStatus = "achieved" | "partial" | "missed";
+
+export interface GoalAssessment {
+    participant_email: string;
+    status: GoalStatus;
+    notes?: string;
+    assessed_at: Date;
+}
+
+export interface Goal {
+    id: string;
+    title: string;
+    assessments: GoalAssessment[];
+}
+
+export interface GoalsContent {
+    goals: Goal[];
+}
+
+export interface LinkPreview {
+    title?: string;
+    description?: string;
+    captured_at: Date;
+}
+
+export interface LinkContent {
+    url: string;
+    preview?: LinkPreview;
+}
+
 export interface APIResponseType<T> {
     message: string;
     data: T;
@@ -50,7 +134,6 @@ export interface PresignedAWSResponse {
     presigned_url: string;
     object_key: string;
     content_type: ContentType;
-    final_url: string;
 }
 
 export interface PaginationMetadata {
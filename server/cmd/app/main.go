@@ -5,42 +5,91 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"time"
 
-	"github.com/pateldivyesh1323/futflare/server/internal/awslib"
+	"github.com/pateldivyesh1323/futflare/server/internal/config"
 	"github.com/pateldivyesh1323/futflare/server/internal/database"
+	"github.com/pateldivyesh1323/futflare/server/internal/handlers"
 	"github.com/pateldivyesh1323/futflare/server/internal/router"
 	"github.com/pateldivyesh1323/futflare/server/internal/scheduler"
+	"github.com/pateldivyesh1323/futflare/server/internal/storage"
+	"github.com/pateldivyesh1323/futflare/server/internal/store"
 	"github.com/rs/cors"
 )
 
-var capsuleCollection = database.Database.Collection("capsule")
-
 func main() {
 	fmt.Println("Server fired on http://localhost:8000...")
 
-	// Initialize AWS services
-
-	awslib.Initialize(context.Background())
-
-	// Capsule opener
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	err := scheduler.CreateIndexes(ctx, capsuleCollection)
+	db, err := database.Connect(ctx)
+	if err != nil {
+		log.Fatalf("Failed to connect to MongoDB: %v", err)
+	}
+
+	capsules := store.NewMongoCapsuleStore(db)
+
+	migrated, err := capsules.MigrateStatus(ctx)
+	if err != nil {
+		log.Fatalf("Failed to migrate capsule status: %v", err)
+	}
+	if migrated > 0 {
+		log.Printf("Migrated status of %d capsules", migrated)
+	}
+
+	err = capsules.EnsureIndexes(ctx)
 	if err != nil {
 		log.Fatalf("Failed to create indexes: %v", err)
 	}
 
-	go scheduler.UpdateCapsuleOpenStatus(ctx, capsuleCollection)
+	uploads := store.NewMongoUploadSessionStore(db)
+
+	err = uploads.EnsureIndexes(ctx)
+	if err != nil {
+		log.Fatalf("Failed to create upload session indexes: %v", err)
+	}
+
+	files, err := storage.Open(ctx)
+	if err != nil {
+		log.Fatalf("Failed to open %s storage: %v", config.StorageBackend, err)
+	}
+
+	// Capsule opener
+	go scheduler.UpdateCapsuleOpenStatus(ctx, capsules, scheduler.LogReminder{})
+
+	// Abandoned multipart uploads
+	go scheduler.AbortExpiredUploads(ctx, uploads, files, time.Hour)
+
+	h := handlers.NewHandler(capsules, uploads, files)
+
+	// Media garbage collector
+	h.MediaDeletions = scheduler.NewMediaDeletionQueue()
+	go h.MediaDeletions.Run(ctx, capsules, files)
+	go scheduler.RunMediaGC(ctx, capsules, files, config.MediaGCInterval, scheduler.MediaGCOptions{
+		GracePeriod:        config.MediaGCGracePeriod,
+		PendingGracePeriod: handlers.UPLOAD_SESSION_EXPIRY,
+		DryRun:             config.MediaGCDryRun,
+	})
+
+	// Link previews of sealed capsules
+	h.LinkPreviews = scheduler.NewLinkPreviewQueue()
+	go h.LinkPreviews.Run(ctx, capsules, h.Previews)
 
 	// Router
 	c := cors.New(cors.Options{
 		AllowedOrigins:   []string{"*"}, // Allow all origins
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+		AllowedMethods:   []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization", "Tus-Resumable", "Upload-Length", "Upload-Metadata", "Upload-Offset"},
+		ExposedHeaders:   []string{"Location", "Tus-Resumable", "Tus-Version", "Tus-Extension", "Tus-Max-Size", "Upload-Offset", "Upload-Length"},
 		AllowCredentials: true,
 	})
-	r := router.NewRouter()
-	handler := c.Handler(r)
+	mux := http.NewServeMux()
+	mux.Handle("/", router.NewRouter(h))
+	// Signed URLs of the local storage carry their own authorization.
+	if local, ok := files.(*storage.LocalStore); ok {
+		mux.Handle(storage.LocalPathPrefix, local)
+	}
+	handler := c.Handler(mux)
 	log.Fatal(http.ListenAndServe(":8000", handler))
 }
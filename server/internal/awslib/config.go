@@ -6,6 +6,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 )
 
 type Config struct {
@@ -13,6 +14,12 @@ type Config struct {
 	AccessKey string
 	SecretKey string
 	Bucket    string
+	// Endpoint points the S3 client at an S3 compatible server such as
+	// MinIO. It is empty for AWS.
+	Endpoint string
+	// UsePathStyle addresses buckets as a path instead of a subdomain, most
+	// S3 compatible servers require it.
+	UsePathStyle bool
 }
 
 func NewConfig(region, accessKey, secretKey, bucket string) *Config {
@@ -34,3 +41,17 @@ func (c *Config) LoadAWSConfig(ctx context.Context) (aws.Config, error) {
 
 	return cfg, err
 }
+
+func (c *Config) NewS3Client(ctx context.Context) (*s3.Client, error) {
+	cfg, err := c.LoadAWSConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if c.Endpoint != "" {
+			o.BaseEndpoint = aws.String(c.Endpoint)
+		}
+		o.UsePathStyle = c.UsePathStyle
+	}), nil
+}
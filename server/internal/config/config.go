@@ -3,26 +3,39 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 var (
-	MongoDBURL      string
-	MongoDBDatabase string
-	AuthDomain      string
-	AuthAudience    string
-	AuthSecret      string
-	AuthClientId    string
-	AWSRegion       string
-	AWSAccessKey    string
-	AWSSecretKey    string
-	AWSS3Bucket     string
+	MongoDBURL          string
+	MongoDBDatabase     string
+	AuthDomain          string
+	AuthAudience        string
+	AuthSecret          string
+	AuthClientId        string
+	AWSRegion           string
+	AWSAccessKey        string
+	AWSSecretKey        string
+	AWSS3Bucket         string
+	AWSS3Endpoint       string
+	AWSS3UsePathStyle   bool
+	StorageBackend      string
+	LocalStorageDir     string
+	LocalStorageURL     string
+	LocalStorageSecret  string
+	CapsuleEditCutoff   time.Duration
+	CheckInReminderLead time.Duration
+	MediaGCInterval     time.Duration
+	MediaGCGracePeriod  time.Duration
+	MediaGCDryRun       bool
 )
 
 func init() {
 	if err := godotenv.Load(); err != nil {
-		log.Fatal("Error loading .env file")
+		log.Println("No .env file loaded, reading configuration from the environment")
 	}
 	MongoDBURL = os.Getenv("MONGODB_URL")
 	MongoDBDatabase = os.Getenv("MONGODB_DATABASE_NAME")
@@ -34,4 +47,52 @@ func init() {
 	AWSAccessKey = os.Getenv("AWS_ACCESS_KEY")
 	AWSSecretKey = os.Getenv("AWS_SECRET_KEY")
 	AWSS3Bucket = os.Getenv("AWS_S3_BUCKET")
+	AWSS3Endpoint = os.Getenv("AWS_S3_ENDPOINT")
+	AWSS3UsePathStyle = getBool("AWS_S3_USE_PATH_STYLE", false)
+	StorageBackend = getString("STORAGE_BACKEND", "s3")
+	LocalStorageDir = getString("LOCAL_STORAGE_DIR", "data/storage")
+	LocalStorageURL = getString("LOCAL_STORAGE_URL", "http://localhost:8000")
+	LocalStorageSecret = os.Getenv("LOCAL_STORAGE_SECRET")
+	CapsuleEditCutoff = getDuration("CAPSULE_EDIT_CUTOFF", time.Hour)
+	CheckInReminderLead = getDuration("CHECK_IN_REMINDER_LEAD", 48*time.Hour)
+	MediaGCInterval = getDuration("MEDIA_GC_INTERVAL", 24*time.Hour)
+	MediaGCGracePeriod = getDuration("MEDIA_GC_GRACE_PERIOD", 24*time.Hour)
+	MediaGCDryRun = getBool("MEDIA_GC_DRY_RUN", true)
+}
+
+func getString(key string, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func getDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid duration for %s: %v, using %s", key, err, fallback)
+		return fallback
+	}
+
+	return d
+}
+
+func getBool(key string, fallback bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Invalid boolean for %s: %v, using %t", key, err, fallback)
+		return fallback
+	}
+
+	return b
 }
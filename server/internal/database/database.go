@@ -3,25 +3,22 @@ package database
 import (
 	"context"
 	"fmt"
-	"log"
 
 	"github.com/pateldivyesh1323/futflare/server/internal/config"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var Database *mongo.Database
-
-func init() {
+func Connect(ctx context.Context) (*mongo.Database, error) {
 	clientOptions := options.Client().ApplyURI(config.MongoDBURL)
-	client, err := mongo.Connect(context.Background(), clientOptions)
+	client, err := mongo.Connect(ctx, clientOptions)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	err = client.Ping(context.Background(), nil)
+	err = client.Ping(ctx, nil)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	Database = client.Database(config.MongoDBDatabase)
 	fmt.Println("Connected to MongoDB")
+	return client.Database(config.MongoDBDatabase), nil
 }
@@ -1,52 +1,68 @@
 package handlers
 
 import (
-	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"math"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/gorilla/mux"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo/options"
 
-	"github.com/pateldivyesh1323/futflare/server/internal/awslib"
+	"github.com/pateldivyesh1323/futflare/server/internal/markdown"
 	"github.com/pateldivyesh1323/futflare/server/internal/model"
+	"github.com/pateldivyesh1323/futflare/server/internal/store"
 	"github.com/pateldivyesh1323/futflare/server/internal/utils"
 
 	"github.com/pateldivyesh1323/futflare/server/internal/config"
-	"github.com/pateldivyesh1323/futflare/server/internal/database"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
-var capsuleCollection = database.Database.Collection("capsule")
-
 const (
 	PRESIGNED_URL_EXPIRY = 15 * time.Minute
+	MAX_CONTENT_ITEMS    = 10
 )
 
+// ALLOWED_AUDIO_TYPES lists the MIME types accepted for audio uploads.
+var ALLOWED_AUDIO_TYPES = []string{
+	"audio/mpeg",
+	"audio/mp4",
+	"audio/aac",
+	"audio/ogg",
+	"audio/wav",
+	"audio/webm",
+}
+
+// MAX_UPLOAD_SIZES limits the size in bytes of uploads per content type.
+// Uploads in parts may be larger, see MAX_MULTIPART_UPLOAD_SIZES.
+var MAX_UPLOAD_SIZES = map[model.ContentType]int64{
+	model.ContentTypeImage: 10 << 20,
+	model.ContentTypeAudio: 50 << 20,
+	model.ContentTypeFile:  100 << 20,
+	model.ContentTypeVideo: 500 << 20,
+}
+
 type PresignedURLRequest struct {
 	ContentType string `json:"content_type"`
 	FileName    string `json:"file_name"`
 	FileType    string `json:"file_type"`
+	FileSize    int64  `json:"file_size"`
 }
 
 type PresignedURLResponse struct {
 	URL         string `json:"presigned_url"`
 	ObjectKey   string `json:"object_key"`
 	ContentType string `json:"content_type"`
-	FinalURL    string `json:"final_url"`
 }
 
-func GeneratePresignedURL(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GeneratePresignedURL(w http.ResponseWriter, r *http.Request) {
 	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer")
-	_, _, err := utils.GetIDFromToken(token)
+	userId, _, err := utils.GetIDFromToken(token)
 	if err != nil {
 		utils.SendJSONResponse(w, http.StatusUnauthorized, err.Error(), nil)
 		return
@@ -58,48 +74,70 @@ func GeneratePresignedURL(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.ContentType != "image" && req.ContentType != "video" {
-		utils.SendJSONResponse(w, http.StatusBadRequest, "Content type must be 'image' or 'video'", nil)
-		return
-	}
-
-	s3Client, err := awslib.GetS3Client()
-	if err != nil {
-		utils.SendJSONResponse(w, http.StatusInternalServerError, "Failed to initialize AWS S3 client", nil)
+	if err := validatePresignedURLRequest(req, false); err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, err.Error(), nil)
 		return
 	}
 
-	objectKey := primitive.NewObjectID().Hex() + "-" + req.FileName
-
-	presignClient := s3.NewPresignClient(s3Client)
-	presignReq, err := presignClient.PresignPutObject(context.Background(), &s3.PutObjectInput{
-		Bucket:      aws.String(config.AWSS3Bucket),
-		Key:         aws.String(objectKey),
-		ContentType: aws.String(req.FileType),
-	}, s3.WithPresignExpires(PRESIGNED_URL_EXPIRY))
+	objectKey := newObjectKey(userId, req.FileName)
 
+	url, err := h.Files.PresignPut(r.Context(), objectKey, req.FileType, req.FileSize, PRESIGNED_URL_EXPIRY)
 	if err != nil {
 		utils.SendJSONResponse(w, http.StatusInternalServerError, "Failed to generate pre-signed URL", nil)
 		return
 	}
 
-	objectURL := "https://" + config.AWSS3Bucket + ".s3.amazonaws.com/" + objectKey
-
 	response := PresignedURLResponse{
-		URL:         presignReq.URL,
+		URL:         url,
 		ObjectKey:   objectKey,
 		ContentType: req.ContentType,
-		FinalURL:    objectURL,
 	}
 
 	utils.SendJSONResponse(w, http.StatusOK, "Pre-signed URL generated successfully", response)
 }
 
+// validatePresignedURLRequest checks an upload request, multipart selects
+// the size limits of uploads in parts.
+func validatePresignedURLRequest(req PresignedURLRequest, multipart bool) error {
+	contentType := model.ContentType(req.ContentType)
+
+	switch contentType {
+	case model.ContentTypeImage, model.ContentTypeVideo, model.ContentTypeFile:
+	case model.ContentTypeAudio:
+		// Browsers append codec parameters, e.g. "audio/webm;codecs=opus".
+		mimeType, _, _ := strings.Cut(req.FileType, ";")
+		if !slices.Contains(ALLOWED_AUDIO_TYPES, strings.TrimSpace(strings.ToLower(mimeType))) {
+			return fmt.Errorf("Unsupported audio file type: %s", req.FileType)
+		}
+	default:
+		return errors.New("Content type must be 'image', 'video', 'audio' or 'file'")
+	}
+
+	if req.FileName == "" {
+		return errors.New("File name is required")
+	}
+
+	// The size is signed into the URL, so S3 rejects uploads of another size.
+	if req.FileSize <= 0 {
+		return errors.New("File size is required")
+	}
+
+	if maxSize := maxUploadSize(contentType, multipart); req.FileSize > maxSize {
+		return fmt.Errorf("%s uploads cannot be larger than %d MB", contentType, maxSize>>20)
+	}
+
+	if multipart && (req.FileSize+MULTIPART_PART_SIZE-1)/MULTIPART_PART_SIZE > MAX_MULTIPART_PARTS {
+		return fmt.Errorf("Uploads cannot have more than %d parts", MAX_MULTIPART_PARTS)
+	}
+
+	return nil
+}
+
 type ErrorResponse struct {
 	Error string `json:"error"`
 }
 
-func CreateCapsule(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) CreateCapsule(w http.ResponseWriter, r *http.Request) {
 	var c model.Capsule
 	err := json.NewDecoder(r.Body).Decode(&c)
 	if err != nil {
@@ -107,107 +145,437 @@ func CreateCapsule(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if c.Title == "" || c.Description == "" || c.ScheduledOpenDate.IsZero() || len(c.ContentItems) == 0 {
-		utils.SendJSONResponse(w, http.StatusBadRequest, "Please fill all required fields and add at least one content item", nil)
+	switch c.Status {
+	case model.CapsuleStatusDraft:
+		if status, err := validateDraft(&c); err != nil {
+			utils.SendJSONResponse(w, status, err.Error(), nil)
+			return
+		}
+
+	case "", model.CapsuleStatusSealed:
+		c.Status = model.CapsuleStatusSealed
+		if status, err := validateCapsule(&c); err != nil {
+			utils.SendJSONResponse(w, status, err.Error(), nil)
+			return
+		}
+
+	default:
+		utils.SendJSONResponse(w, http.StatusBadRequest, "Capsules can only be created as draft or sealed", nil)
 		return
 	}
 
-	if len(c.ContentItems) > 10 {
-		utils.SendJSONResponse(w, http.StatusForbidden, "You can add maximum 10 content items!", nil)
+	if status, err := validateContentItems(c.ContentItems); err != nil {
+		utils.SendJSONResponse(w, status, err.Error(), nil)
 		return
 	}
 
-	if c.ScheduledOpenDate.Before(time.Now()) {
-		utils.SendJSONResponse(w, http.StatusBadRequest, "Please select future date and time", nil)
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer")
+	id, _, err := utils.GetIDFromToken(token)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, err.Error(), nil)
 		return
 	}
 
-	for _, item := range c.ContentItems {
-		switch item.Type {
-		case model.ContentTypeMessage:
-			msgContent, ok := item.Content.(model.MessageContent)
-			if !ok {
-				utils.SendJSONResponse(w, http.StatusBadRequest, "Invalid message content format", nil)
-				return
-			}
+	if status, err := h.verifyUploads(r.Context(), id, c.ContentItems, nil); err != nil {
+		utils.SendJSONResponse(w, status, err.Error(), nil)
+		return
+	}
 
-			if msgContent.Text == "" {
-				utils.SendJSONResponse(w, http.StatusBadRequest, "Message text cannot be empty", nil)
-				return
-			}
+	c.ID = primitive.NewObjectID()
+	if c.Quorum != nil {
+		c.Quorum.Approvals = nil
+	}
+	if c.Lock != nil {
+		c.Lock.UnlockedBy = nil
+		if err := c.Lock.HashPassphrase(); err != nil {
+			utils.SendJSONResponse(w, http.StatusBadRequest, "Invalid passphrase", nil)
+			return
+		}
+	}
+	if c.DeadMansSwitch != nil {
+		// Sealing a draft restarts the switch.
+		c.DeadMansSwitch.CheckIn(time.Now(), config.CheckInReminderLead)
+	}
+	if c.Recurrence != nil {
+		c.SeriesID = &c.ID
+	}
+	if err := prepareContentItems(c.ContentItems, nil); err != nil {
+		utils.SendJSONResponse(w, http.StatusInternalServerError, "Internal server error", nil)
+		return
+	}
+	c.Creator = id
+	c.IsOpened = false
+	c.NextRevealAt = c.NextReveal(time.Now())
+	c.CreatedAt = time.Now()
+	err = h.Capsules.Create(r.Context(), &c)
 
-		case model.ContentTypeImage:
-			imgContent, ok := item.Content.(model.ImageContent)
-			if !ok {
-				utils.SendJSONResponse(w, http.StatusBadRequest, "Invalid image content format", nil)
-				return
-			}
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, "Internal server error", nil)
+		return
+	}
 
-			if imgContent.URL == "" {
-				utils.SendJSONResponse(w, http.StatusBadRequest, "Image URL cannot be empty", nil)
-				return
-			}
+	if c.Status == model.CapsuleStatusSealed {
+		h.queueLinkPreviews(&c)
+	}
 
-		case model.ContentTypeVideo:
-			vidContent, ok := item.Content.(model.VideoContent)
-			if !ok {
-				utils.SendJSONResponse(w, http.StatusBadRequest, "Invalid video content format", nil)
-				return
-			}
+	utils.SendJSONResponse(w, http.StatusCreated, "Successfully created capsule", newCapsuleSummary(c))
+}
 
-			if vidContent.URL == "" {
-				utils.SendJSONResponse(w, http.StatusBadRequest, "Video URL cannot be empty", nil)
-				return
-			}
+// validateCapsule checks that a capsule is complete enough to be sealed. The
+// returned status is the HTTP status to respond with when it is not.
+func validateCapsule(c *model.Capsule) (int, error) {
+	// Capsules opened by quorum alone or by a dead man's switch do not need an
+	// open date.
+	needsDate := (c.Quorum == nil || c.Quorum.RequireDate) && c.DeadMansSwitch == nil
 
-		default:
-			utils.SendJSONResponse(w, http.StatusBadRequest, "Invalid content type", nil)
-			return
+	if c.Title == "" || c.Description == "" || (needsDate && c.ScheduledOpenDate.IsZero()) || len(c.ContentItems) == 0 {
+		return http.StatusBadRequest, errors.New("Please fill all required fields and add at least one content item")
+	}
+
+	if c.Recurrence != nil && c.ScheduledOpenDate.IsZero() {
+		return http.StatusBadRequest, errors.New("Recurring capsules need an open date")
+	}
+
+	if c.Quorum != nil && c.Quorum.Required > len(c.ParticipantEmails) {
+		return http.StatusBadRequest, errors.New("Quorum cannot require more keys than there are participants")
+	}
+
+	return validateDraft(c)
+}
+
+// validateDraft checks the limits that apply to every capsule, drafts
+// included. Drafts may leave everything but the title empty.
+func validateDraft(c *model.Capsule) (int, error) {
+	if c.Title == "" {
+		return http.StatusBadRequest, errors.New("Please add a title")
+	}
+
+	if len(c.ContentItems) > MAX_CONTENT_ITEMS {
+		return http.StatusForbidden, fmt.Errorf("You can add maximum %d content items!", MAX_CONTENT_ITEMS)
+	}
+
+	if !c.ScheduledOpenDate.IsZero() && c.ScheduledOpenDate.Before(time.Now()) {
+		return http.StatusBadRequest, errors.New("Please select future date and time")
+	}
+
+	if c.Quorum != nil && c.Quorum.Required < 1 {
+		return http.StatusBadRequest, errors.New("Quorum must require at least one key")
+	}
+
+	if c.Lock != nil && c.Lock.Hash == "" && strings.TrimSpace(c.Lock.Passphrase) == "" {
+		return http.StatusBadRequest, errors.New("Please set a passphrase for the lock")
+	}
+
+	if c.Geofence != nil {
+		if err := c.Geofence.Validate(); err != nil {
+			return http.StatusBadRequest, errors.New("Invalid geofence")
+		}
+	}
+
+	if c.DeadMansSwitch != nil {
+		if c.DeadMansSwitch.CheckInDays < 1 || c.DeadMansSwitch.GraceDays < 0 {
+			return http.StatusBadRequest, errors.New("Check-in interval must be at least one day")
+		}
+
+		if c.Quorum != nil || c.Recurrence != nil || !c.ScheduledOpenDate.IsZero() {
+			return http.StatusBadRequest, errors.New("Dead man's switch capsules cannot have an open date, quorum or recurrence")
+		}
+	}
+
+	if c.Recurrence != nil {
+		if err := c.Recurrence.Validate(); err != nil {
+			return http.StatusBadRequest, errors.New("Invalid recurrence")
+		}
+	}
+
+	for _, item := range c.ContentItems {
+		if item.RevealAt != nil && !c.ScheduledOpenDate.IsZero() && item.RevealAt.Before(c.ScheduledOpenDate) {
+			return http.StatusBadRequest, errors.New("Content items cannot be revealed before the capsule opens")
 		}
 	}
 
 	if len(c.ParticipantEmails) > 10 {
-		utils.SendJSONResponse(w, http.StatusForbidden, "You can add maximum 10 participants!", nil)
-		return
+		return http.StatusForbidden, errors.New("You can add maximum 10 participants!")
 	}
 
 	for _, email := range c.ParticipantEmails {
 		if !utils.IsEmailValid(email) {
-			utils.SendJSONResponse(w, http.StatusBadRequest, "Invalid email", nil)
+			return http.StatusBadRequest, errors.New("Invalid email")
+		}
+	}
+
+	return http.StatusOK, nil
+}
+
+func validateContentItems(items []model.ContentItem) (int, error) {
+	for _, item := range items {
+		if err := item.Validate(); err != nil {
+			return http.StatusBadRequest, err
+		}
+	}
+
+	return http.StatusOK, nil
+}
+
+// prepareContentItems resets the server managed state of new items, such as
+// the IDs and answers of predictions or the rendered HTML of messages.
+// existing holds the stored items when a capsule is edited.
+func prepareContentItems(items []model.ContentItem, existing []model.ContentItem) error {
+	preparePredictions(items, existing)
+	prepareGoals(items)
+	prepareLinks(items, existing)
+	return prepareMessages(items)
+}
+
+// prepareMessages stores the sanitized HTML of Markdown messages, so clients
+// never have to render user input themselves.
+func prepareMessages(items []model.ContentItem) error {
+	for i := range items {
+		message, ok := items[i].Content.(*model.MessageContent)
+		if !ok {
+			continue
+		}
+
+		message.HTML = ""
+		if message.Format != model.MessageFormatMarkdown {
+			continue
+		}
+
+		html, err := markdown.ToHTML(message.Text)
+		if err != nil {
+			return err
+		}
+		message.HTML = html
+	}
+
+	return nil
+}
+
+// UpdateCapsuleRequest holds the fields a creator may change before a capsule
+// opens. Fields left out of the request keep their current value. The open
+// date can only be changed while the capsule is a draft.
+type UpdateCapsuleRequest struct {
+	Title             *string              `json:"title"`
+	Description       *string              `json:"description"`
+	ParticipantEmails *[]string            `json:"participant_emails"`
+	ScheduledOpenDate *time.Time           `json:"scheduled_open_date"`
+	ContentItems      *[]model.ContentItem `json:"content_items"`
+}
+
+func (h *Handler) UpdateCapsule(w http.ResponseWriter, r *http.Request) {
+	var req UpdateCapsuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, "Invalid request body", nil)
+		return
+	}
+
+	capsule, ok := h.getOwnCapsule(w, r)
+	if !ok {
+		return
+	}
+
+	switch capsule.Status {
+	case model.CapsuleStatusDraft:
+		// Drafts can be edited freely until they are sealed.
+
+	case model.CapsuleStatusSealed:
+		if !capsule.ScheduledOpenDate.IsZero() && time.Now().Add(config.CapsuleEditCutoff).After(capsule.ScheduledOpenDate) {
+			utils.SendJSONResponse(w, http.StatusConflict, "Capsule is too close to its open date to be edited", nil)
 			return
 		}
+
+		if req.ScheduledOpenDate != nil {
+			utils.SendJSONResponse(w, http.StatusConflict, "Open date cannot be changed once the capsule is sealed", nil)
+			return
+		}
+
+	default:
+		utils.SendJSONResponse(w, http.StatusConflict, "Only draft and sealed capsules can be edited", nil)
+		return
 	}
 
-	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer")
-	id, _, err := utils.GetIDFromToken(token)
-	if err != nil {
-		utils.SendJSONResponse(w, http.StatusBadRequest, err.Error(), nil)
+	if req.Title != nil {
+		capsule.Title = *req.Title
+	}
+	if req.Description != nil {
+		capsule.Description = *req.Description
+	}
+	if req.ParticipantEmails != nil {
+		capsule.ParticipantEmails = *req.ParticipantEmails
+
+		// Keys turned by removed participants no longer count.
+		if capsule.Quorum != nil {
+			approvals := []string{}
+			for _, email := range capsule.ParticipantEmails {
+				if capsule.Quorum.HasApproved(email) {
+					approvals = append(approvals, email)
+				}
+			}
+			capsule.Quorum.Approvals = approvals
+		}
+	}
+	if req.ScheduledOpenDate != nil {
+		capsule.ScheduledOpenDate = *req.ScheduledOpenDate
+	}
+	if req.ContentItems != nil {
+		if status, err := validateContentItems(*req.ContentItems); err != nil {
+			utils.SendJSONResponse(w, status, err.Error(), nil)
+			return
+		}
+
+		if status, err := h.verifyUploads(r.Context(), capsule.Creator, *req.ContentItems, capsule.ContentItems); err != nil {
+			utils.SendJSONResponse(w, status, err.Error(), nil)
+			return
+		}
+
+		// The creator replaces their own items, contributions made by
+		// participants are kept.
+		items := *req.ContentItems
+		if err := prepareContentItems(items, capsule.ContentItems); err != nil {
+			utils.SendJSONResponse(w, http.StatusInternalServerError, "Internal server error", nil)
+			return
+		}
+		for _, item := range capsule.ContentItems {
+			if item.ContributedBy != "" {
+				items = append(items, item)
+			}
+		}
+		capsule.ContentItems = items
+	}
+
+	validate := validateCapsule
+	if capsule.Status == model.CapsuleStatusDraft {
+		validate = validateDraft
+	}
+
+	if status, err := validate(capsule); err != nil {
+		utils.SendJSONResponse(w, status, err.Error(), nil)
 		return
 	}
 
-	c.ID = primitive.NewObjectID()
-	c.Creator = id
-	c.IsOpened = false
-	c.CreatedAt = time.Now()
-	_, err = capsuleCollection.InsertOne(context.Background(), c)
+	capsule.NextRevealAt = capsule.NextReveal(time.Now())
 
+	err := h.Capsules.Update(r.Context(), capsule)
 	if err != nil {
-		utils.SendJSONResponse(w, http.StatusBadRequest, "Internal server error", nil)
+		if err == store.ErrNotFound {
+			utils.SendJSONResponse(w, http.StatusConflict, "Capsule changed while it was being edited, please try again", nil)
+		} else {
+			utils.SendJSONResponse(w, http.StatusInternalServerError, "Internal server error", nil)
+		}
 		return
 	}
 
-	utils.SendJSONResponse(w, http.StatusCreated, "Successfully created capsule", nil)
+	if capsule.Status == model.CapsuleStatusSealed {
+		h.queueLinkPreviews(capsule)
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, "Successfully updated capsule", newCapsuleSummary(*capsule))
+}
+
+// getOwnCapsule loads the capsule named in the request path and makes sure the
+// caller created it. It writes the error response itself and reports false
+// when the handler should stop.
+func (h *Handler) getOwnCapsule(w http.ResponseWriter, r *http.Request) (*model.Capsule, bool) {
+	params := mux.Vars(r)
+	id := params["id"]
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer")
+	userId, _, err := utils.GetIDFromToken(token)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusForbidden, "Unauthorized", nil)
+		return nil, false
+	}
+
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, "Invalid capsule ID", nil)
+		return nil, false
+	}
+
+	capsule, err := h.Capsules.GetForUser(r.Context(), objectId, userId, "")
+	if err != nil {
+		if err == store.ErrNotFound {
+			utils.SendJSONResponse(w, http.StatusNotFound, "Capsule not found", nil)
+		} else {
+			utils.SendJSONResponse(w, http.StatusInternalServerError, "Internal server error", nil)
+		}
+		return nil, false
+	}
+
+	if capsule.Creator != userId {
+		utils.SendJSONResponse(w, http.StatusForbidden, "Only the creator can change this capsule", nil)
+		return nil, false
+	}
+
+	return capsule, true
+}
+
+// getMemberCapsule loads the capsule addressed by the request for its creator
+// or a participant and returns the caller's email. Participants only get
+// drafts when contributing to them. Opened locked capsules are refused until
+// the caller answered the passphrase, opened fenced capsules unless the
+// caller is inside the geofence. It writes the error response itself.
+func (h *Handler) getMemberCapsule(w http.ResponseWriter, r *http.Request, contributing bool) (*model.Capsule, string, bool) {
+	params := mux.Vars(r)
+	id := params["id"]
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer")
+	userId, fullId, err := utils.GetIDFromToken(token)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusForbidden, "Unauthorized", nil)
+		return nil, "", false
+	}
+
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, "Invalid capsule ID", nil)
+		return nil, "", false
+	}
+
+	userDetails, err := h.GetUser(fullId)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusInternalServerError, "Internal server error", nil)
+		return nil, "", false
+	}
+
+	get := h.Capsules.GetForUser
+	if contributing {
+		get = h.Capsules.GetForContributor
+	}
+
+	capsule, err := get(r.Context(), objectId, userId, userDetails.Email)
+	if err != nil {
+		if err == store.ErrNotFound {
+			utils.SendJSONResponse(w, http.StatusNotFound, "Capsule not found", nil)
+		} else {
+			utils.SendJSONResponse(w, http.StatusInternalServerError, "Internal server error", nil)
+		}
+		return nil, "", false
+	}
+
+	if capsule.IsOpened && capsule.Lock != nil && capsule.Creator != userId && !capsule.Lock.IsUnlockedBy(userId) {
+		utils.SendJSONResponse(w, http.StatusForbidden, "Unlock the capsule first", nil)
+		return nil, "", false
+	}
+
+	// Like GetCapsule, fenced capsules take the lat and lng query parameters.
+	if capsule.IsOpened && capsule.Geofence != nil && capsule.Creator != userId && !h.unlockGeofence(r, capsule, userId) {
+		utils.SendJSONResponse(w, http.StatusForbidden, "Open the capsule inside its geofence first", nil)
+		return nil, "", false
+	}
+
+	return capsule, userDetails.Email, true
 }
 
 type PaginationResponse struct {
-	Data         []primitive.M `json:"data"`
-	TotalCount   int64         `json:"totalCount"`
-	CurrentCount int           `json:"currentCount"`
-	TotalPages   int           `json:"totalPages"`
-	CurrentPage  int           `json:"currentPage"`
+	Data         []CapsuleSummary `json:"data"`
+	TotalCount   int64            `json:"totalCount"`
+	CurrentCount int              `json:"currentCount"`
+	TotalPages   int              `json:"totalPages"`
+	CurrentPage  int              `json:"currentPage"`
 }
 
-func GetAllCapsules(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetAllCapsules(w http.ResponseWriter, r *http.Request) {
 	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer")
 	id, fullId, err := utils.GetIDFromToken(token)
 	if err != nil {
@@ -215,7 +583,7 @@ func GetAllCapsules(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userDetails, err := getUserById(fullId)
+	userDetails, err := h.GetUser(fullId)
 
 	if err != nil {
 		utils.SendJSONResponse(w, http.StatusInternalServerError, "Internal server error", nil)
@@ -237,39 +605,16 @@ func GetAllCapsules(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	searchQuery := r.URL.Query().Get("searchQuery")
-	sortParam := r.URL.Query().Get("sortBy")
-	sortOrder := -1
-	if sortParam == "oldest" {
-		sortOrder = 1
+	filter := store.ListFilter{
+		UserID: id,
+		Email:  userDetails.Email,
+		Search: r.URL.Query().Get("searchQuery"),
+		Oldest: r.URL.Query().Get("sortBy") == "oldest",
+		Skip:   int64((page - 1) * limit),
+		Limit:  int64(limit),
 	}
 
-	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: sortOrder}}).
-		SetSkip(int64((page - 1) * limit)).
-		SetLimit(int64(limit))
-
-	filter := bson.M{
-		"$or": []bson.M{
-			{"creator": id},
-			{"participant_emails": bson.M{"$elemMatch": bson.M{"$eq": userDetails.Email}}},
-		},
-	}
-
-	if searchQuery != "" {
-		filter = bson.M{
-			"$and": []bson.M{
-				filter,
-				{
-					"$or": []bson.M{
-						{"title": bson.M{"$regex": searchQuery, "$options": "i"}},
-						{"description": bson.M{"$regex": searchQuery, "$options": "i"}},
-					},
-				},
-			},
-		}
-	}
-
-	totalCount, err := capsuleCollection.CountDocuments(context.Background(), filter)
+	results, totalCount, err := h.Capsules.List(r.Context(), filter)
 	if err != nil {
 		utils.SendJSONResponse(w, http.StatusInternalServerError, "Internal server error", nil)
 		return
@@ -277,34 +622,7 @@ func GetAllCapsules(w http.ResponseWriter, r *http.Request) {
 
 	totalPages := int(math.Ceil(float64(totalCount) / float64(limit)))
 
-	cursor, err := capsuleCollection.Find(context.Background(), filter, opts)
-	if err != nil {
-		utils.SendJSONResponse(w, http.StatusInternalServerError, "Internal server error", nil)
-		return
-	}
-	defer cursor.Close(context.Background())
-
-	var capsules []primitive.M
-
-	for cursor.Next(context.Background()) {
-		var cap bson.M
-		err := cursor.Decode(&cap)
-		if err != nil {
-			utils.SendJSONResponse(w, http.StatusInternalServerError, "Internal server error", nil)
-			return
-		}
-		filteredCap := map[string]interface{}{
-			"_id":                 cap["_id"],
-			"created_at":          cap["created_at"],
-			"creator":             cap["creator"],
-			"title":               cap["title"],
-			"description":         cap["description"],
-			"is_opened":           cap["is_opened"],
-			"participant_emails":  cap["participant_emails"],
-			"scheduled_open_date": cap["scheduled_open_date"],
-		}
-		capsules = append(capsules, filteredCap)
-	}
+	capsules := newCapsuleSummaries(results)
 
 	response := PaginationResponse{
 		Data:         capsules,
@@ -317,7 +635,7 @@ func GetAllCapsules(w http.ResponseWriter, r *http.Request) {
 	utils.SendJSONResponse(w, http.StatusOK, "Successfully fetched capsules", response)
 }
 
-func GetCapsule(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetCapsule(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	id := params["id"]
 
@@ -328,7 +646,7 @@ func GetCapsule(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userDetails, err := getUserById(fullId)
+	userDetails, err := h.GetUser(fullId)
 
 	if err != nil {
 		utils.SendJSONResponse(w, http.StatusInternalServerError, "Internal server error", nil)
@@ -341,17 +659,11 @@ func GetCapsule(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var capsule bson.M
-	err = capsuleCollection.FindOne(context.Background(), bson.M{
-		"_id": objectID,
-		"$or": []bson.M{
-			{"creator": userID},
-			{"participant_emails": bson.M{"$elemMatch": bson.M{"$eq": userDetails.Email}}},
-		},
-	}).Decode(&capsule)
-
+	// Participants see drafts they may contribute to, their content stays
+	// hidden like that of any unopened capsule.
+	capsule, err := h.Capsules.GetForContributor(r.Context(), objectID, userID, userDetails.Email)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
+		if err == store.ErrNotFound {
 			utils.SendJSONResponse(w, http.StatusNotFound, "Capsule not found", nil)
 		} else {
 			utils.SendJSONResponse(w, http.StatusInternalServerError, "Internal server error", nil)
@@ -359,31 +671,99 @@ func GetCapsule(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	isOpened, ok := capsule["is_opened"].(bool)
-	if !ok {
-		utils.SendJSONResponse(w, http.StatusInternalServerError, "Internal server error", nil)
-		return
+	response := CapsuleDetails{
+		CapsuleSummary: newCapsuleSummary(*capsule),
+		ContentItems:   []model.ContentItem{},
+	}
+
+	// Locked capsules only reveal their content to the creator and to users
+	// who answered the passphrase.
+	locked := capsule.Lock != nil && capsule.Creator != userID && !capsule.Lock.IsUnlockedBy(userID)
+	response.IsLocked = locked
+
+	fenced := capsule.Geofence != nil && capsule.Creator != userID && capsule.IsOpened && !locked
+	if fenced {
+		fenced = !h.unlockGeofence(r, capsule, userID)
+		response.OutsideGeofence = fenced
+	}
+
+	if capsule.Geofence != nil && capsule.Creator == userID {
+		response.LocationUnlocks = capsule.LocationUnlocks
 	}
 
-	response := map[string]interface{}{
-		"_id":                 capsule["_id"],
-		"created_at":          capsule["created_at"],
-		"creator":             capsule["creator"],
-		"title":               capsule["title"],
-		"description":         capsule["description"],
-		"is_opened":           capsule["is_opened"],
-		"participant_emails":  capsule["participant_emails"],
-		"scheduled_open_date": capsule["scheduled_open_date"],
+	if capsule.IsOpened && !locked && !fenced {
+		now := time.Now()
+		for _, item := range capsule.ContentItems {
+			if item.IsRevealed(now) {
+				response.ContentItems = append(response.ContentItems, item)
+			}
+		}
+		response.NextRevealAt = capsule.NextReveal(now)
+		response.PredictionScores = model.ScorePredictions(response.ContentItems)
+		response.GoalsSummary = model.SummarizeGoals(response.ContentItems)
+	}
+
+	// The creator edits a draft by sending their items again, so they get
+	// them back until the capsule is sealed. Contributions are listed apart,
+	// an edit keeps them as they are.
+	if capsule.Status == model.CapsuleStatusDraft && capsule.Creator == userID {
+		for _, item := range capsule.ContentItems {
+			if item.ContributedBy == "" {
+				response.ContentItems = append(response.ContentItems, item)
+			} else {
+				response.Contributions = append(response.Contributions, item)
+			}
+		}
 	}
 
-	if isOpened {
-		response["content_items"] = capsule["content_items"]
+	// Media is only reachable through short lived links minted for users
+	// who may see the content.
+	if err := h.signUploads(r.Context(), append(response.ContentItems, response.Contributions...)); err != nil {
+		utils.SendJSONResponse(w, http.StatusInternalServerError, "Failed to generate download links", nil)
+		return
+	}
+
+	if !capsule.IsOpened {
+		response.Predictions = newPredictionQuestions(capsule.ContentItems, userDetails.Email)
 	}
 
 	utils.SendJSONResponse(w, http.StatusOK, "Successfully fetched capsule", response)
 }
 
-func DeleteCapsule(w http.ResponseWriter, r *http.Request) {
+// unlockGeofence checks the lat and lng query parameters against the capsule's
+// geofence. The coordinates are reported by the client and not verified. The
+// first unlock of every user is recorded for auditing.
+func (h *Handler) unlockGeofence(r *http.Request, capsule *model.Capsule, userID string) bool {
+	lat, err := strconv.ParseFloat(r.URL.Query().Get("lat"), 64)
+	if err != nil {
+		return false
+	}
+
+	lng, err := strconv.ParseFloat(r.URL.Query().Get("lng"), 64)
+	if err != nil {
+		return false
+	}
+
+	distance, inside := capsule.Geofence.Contains(lat, lng)
+	if !inside {
+		return false
+	}
+
+	err = h.Capsules.RecordLocationUnlock(r.Context(), capsule.ID, model.LocationUnlock{
+		UserID:         userID,
+		Latitude:       lat,
+		Longitude:      lng,
+		DistanceMeters: distance,
+		UnlockedAt:     time.Now(),
+	})
+	if err != nil {
+		log.Printf("Failed to record location unlock of capsule %s: %v", capsule.ID.Hex(), err)
+	}
+
+	return true
+}
+
+func (h *Handler) DeleteCapsule(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	id := params["id"]
 
@@ -396,20 +776,29 @@ func DeleteCapsule(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	deleteResult, err := capsuleCollection.DeleteOne(context.Background(), bson.M{
-		"_id":     objectId,
-		"creator": userId,
-	})
-
+	capsule, err := h.Capsules.GetForUser(r.Context(), objectId, userId, "")
 	if err != nil {
-		utils.SendJSONResponse(w, http.StatusInternalServerError, "Internal server error", nil)
+		if err == store.ErrNotFound {
+			utils.SendJSONResponse(w, http.StatusNotFound, "Capsule not found", nil)
+		} else {
+			utils.SendJSONResponse(w, http.StatusInternalServerError, "Internal server error", nil)
+		}
 		return
 	}
 
-	if deleteResult.DeletedCount == 0 {
-		utils.SendJSONResponse(w, http.StatusNotFound, "Capsule not found", nil)
+	err = h.Capsules.Delete(r.Context(), objectId, userId)
+	if err != nil {
+		if err == store.ErrNotFound {
+			utils.SendJSONResponse(w, http.StatusNotFound, "Capsule not found", nil)
+		} else {
+			utils.SendJSONResponse(w, http.StatusInternalServerError, "Internal server error", nil)
+		}
 		return
 	}
 
+	if h.MediaDeletions != nil {
+		h.MediaDeletions.Enqueue(uploadKeys(capsule.ContentItems)...)
+	}
+
 	utils.SendJSONResponse(w, http.StatusOK, "Capsule deleted successfully", nil)
 }
@@ -0,0 +1,451 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/pateldivyesh1323/futflare/server/internal/config"
+	"github.com/pateldivyesh1323/futflare/server/internal/model"
+	"github.com/pateldivyesh1323/futflare/server/internal/storage"
+	"github.com/pateldivyesh1323/futflare/server/internal/store"
+)
+
+// testEmails maps the users of the tests to their email addresses.
+var testEmails = map[string]string{
+	"creator":     "creator@example.com",
+	"participant": "participant@example.com",
+	"stranger":    "stranger@example.com",
+}
+
+type testServer struct {
+	capsules *store.MemoryCapsuleStore
+	uploads  *store.MemoryUploadSessionStore
+	files    *storage.LocalStore
+	router   *mux.Router
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	capsules := store.NewMemoryCapsuleStore()
+	uploads := store.NewMemoryUploadSessionStore()
+	files, err := storage.NewLocalStore(t.TempDir(), "http://localhost", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandler(capsules, uploads, files)
+	h.GetUser = func(userId string) (UserDetails, error) {
+		for user, email := range testEmails {
+			if userId == "auth0|"+user {
+				return UserDetails{Email: email}, nil
+			}
+		}
+		return UserDetails{}, errors.New("unknown user")
+	}
+
+	// The routes of router.NewRouter without the Auth0 middleware, the
+	// handlers only read the subject of the token.
+	r := mux.NewRouter()
+	r.HandleFunc("/api/capsule", h.CreateCapsule).Methods("POST")
+	r.HandleFunc("/api/capsule", h.GetAllCapsules).Methods("GET")
+	r.HandleFunc("/api/capsule/{id}", h.GetCapsule).Methods("GET")
+	r.HandleFunc("/api/capsule/{id}", h.UpdateCapsule).Methods("PUT", "PATCH")
+	r.HandleFunc("/api/capsule/{id}/contributions", h.AddContribution).Methods("POST")
+	r.HandleFunc("/api/capsule/{id}/unlock", h.UnlockCapsule).Methods("POST")
+	r.HandleFunc("/api/capsule/{id}/check-in", h.CheckIn).Methods("POST")
+	r.HandleFunc("/api/capsule/{id}/predictions/{predictionId}/answer", h.AnswerPrediction).Methods("POST")
+	r.HandleFunc("/api/capsule/{id}", h.DeleteCapsule).Methods("DELETE")
+	r.HandleFunc("/api/uploader/multipart", h.StartMultipartUpload).Methods("POST")
+	r.HandleFunc("/api/uploader/multipart/{id}", h.GetMultipartUpload).Methods("GET")
+	r.HandleFunc("/api/uploader/multipart/{id}/parts", h.SignMultipartParts).Methods("POST")
+	r.HandleFunc("/api/uploader/multipart/{id}/complete", h.CompleteMultipartUpload).Methods("POST")
+	r.HandleFunc("/api/uploader/multipart/{id}", h.AbortMultipartUpload).Methods("DELETE")
+	r.HandleFunc("/api/tus", h.CreateTusUpload).Methods("POST")
+	r.HandleFunc("/api/tus/{id}", h.HeadTusUpload).Methods("HEAD")
+	r.HandleFunc("/api/tus/{id}", h.PatchTusUpload).Methods("PATCH")
+	r.HandleFunc("/api/tus/{id}", h.DeleteTusUpload).Methods("DELETE")
+	r.PathPrefix(storage.LocalPathPrefix).Handler(files)
+
+	return &testServer{capsules: capsules, uploads: uploads, files: files, router: r}
+}
+
+// testToken returns an unsigned bearer token for user.
+func testToken(user string) string {
+	claims, _ := json.Marshal(map[string]string{"sub": "auth0|" + user})
+	return "Bearer header." + base64.RawURLEncoding.EncodeToString(claims) + ".signature"
+}
+
+// do sends a request as user and decodes the data of the response into out.
+func (s *testServer) do(t *testing.T, user, method, path string, body any, out any) int {
+	t.Helper()
+
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	req := httptest.NewRequest(method, path, &payload)
+	req.Header.Set("Authorization", testToken(user))
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+
+	if out != nil && w.Code < 300 {
+		response := struct {
+			Data json.RawMessage `json:"data"`
+		}{}
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("decoding response: %v", err)
+		}
+		if err := json.Unmarshal(response.Data, out); err != nil {
+			t.Fatalf("decoding response data: %v", err)
+		}
+	}
+
+	return w.Code
+}
+
+// seed stores a capsule created by the creator user.
+func (s *testServer) seed(t *testing.T, title string, status model.CapsuleStatus) *model.Capsule {
+	t.Helper()
+
+	c := &model.Capsule{
+		ID:                primitive.NewObjectID(),
+		Title:             title,
+		Description:       "A capsule for the tests",
+		Creator:           "creator",
+		Status:            status,
+		ParticipantEmails: []string{testEmails["participant"]},
+		ScheduledOpenDate: time.Now().Add(24 * time.Hour),
+		ContentItems:      []model.ContentItem{testMessage("Hello")},
+		CreatedAt:         time.Now(),
+	}
+	if err := s.capsules.Create(context.Background(), c); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func testMessage(text string) model.ContentItem {
+	return model.ContentItem{Type: model.ContentTypeMessage, Content: &model.MessageContent{Text: text}}
+}
+
+func TestCreateCapsule(t *testing.T) {
+	s := newTestServer(t)
+
+	body := map[string]any{
+		"title":               "Graduation",
+		"description":         "Open in a year",
+		"participant_emails":  []string{testEmails["participant"]},
+		"scheduled_open_date": time.Now().Add(365 * 24 * time.Hour),
+		"content_items": []map[string]any{
+			{"type": "message", "content": map[string]any{"text": "Hello future me"}},
+		},
+	}
+
+	var created CapsuleSummary
+	if code := s.do(t, "creator", "POST", "/api/capsule", body, &created); code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d", code, http.StatusCreated)
+	}
+
+	if created.Creator != "creator" || created.Status != model.CapsuleStatusSealed {
+		t.Errorf("created capsule = %+v, want a sealed capsule of creator", created)
+	}
+
+	stored, err := s.capsules.GetForUser(context.Background(), created.ID, "creator", "")
+	if err != nil {
+		t.Fatalf("capsule was not stored: %v", err)
+	}
+	if stored.Title != "Graduation" || len(stored.ContentItems) != 1 {
+		t.Errorf("stored capsule = %+v", stored)
+	}
+}
+
+func TestCreateCapsuleRejectsIncompleteCapsules(t *testing.T) {
+	s := newTestServer(t)
+
+	tests := []struct {
+		name string
+		body map[string]any
+		want int
+	}{
+		{"sealed without content", map[string]any{"title": "Empty", "description": "Nothing", "scheduled_open_date": time.Now().Add(time.Hour)}, http.StatusBadRequest},
+		{"open date in the past", map[string]any{"title": "Late", "description": "Too late", "scheduled_open_date": time.Now().Add(-time.Hour), "content_items": []map[string]any{{"type": "message", "content": map[string]any{"text": "Hi"}}}}, http.StatusBadRequest},
+		{"unknown status", map[string]any{"title": "Odd", "status": "opened"}, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := s.do(t, "creator", "POST", "/api/capsule", tt.body, nil); code != tt.want {
+				t.Errorf("status = %d, want %d", code, tt.want)
+			}
+		})
+	}
+
+	if _, total, _ := s.capsules.List(context.Background(), store.ListFilter{UserID: "creator", Limit: 10}); total != 0 {
+		t.Errorf("%d capsules were stored, want none", total)
+	}
+}
+
+func TestGetCapsule(t *testing.T) {
+	s := newTestServer(t)
+	sealed := s.seed(t, "Sealed", model.CapsuleStatusSealed)
+	draft := s.seed(t, "Draft", model.CapsuleStatusDraft)
+
+	// Content stays hidden until the capsule opens, except from the creator
+	// of a draft who edits it.
+	tests := []struct {
+		name      string
+		user      string
+		capsule   *model.Capsule
+		want      int
+		wantItems int
+	}{
+		{"creator", "creator", sealed, http.StatusOK, 0},
+		{"participant", "participant", sealed, http.StatusOK, 0},
+		{"stranger", "stranger", sealed, http.StatusNotFound, 0},
+		{"creator of draft", "creator", draft, http.StatusOK, 1},
+		{"participant of draft", "participant", draft, http.StatusOK, 0},
+		{"stranger of draft", "stranger", draft, http.StatusNotFound, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var details CapsuleDetails
+			code := s.do(t, tt.user, "GET", "/api/capsule/"+tt.capsule.ID.Hex(), nil, &details)
+			if code != tt.want {
+				t.Fatalf("status = %d, want %d", code, tt.want)
+			}
+			if code != http.StatusOK {
+				return
+			}
+
+			if details.ID != tt.capsule.ID || details.Title != tt.capsule.Title {
+				t.Errorf("got capsule %s %q, want %s %q", details.ID.Hex(), details.Title, tt.capsule.ID.Hex(), tt.capsule.Title)
+			}
+			if len(details.ContentItems) != tt.wantItems {
+				t.Errorf("got %d content items, want %d", len(details.ContentItems), tt.wantItems)
+			}
+		})
+	}
+
+	if code := s.do(t, "creator", "GET", "/api/capsule/not-an-id", nil, nil); code != http.StatusBadRequest {
+		t.Errorf("invalid ID: status = %d, want %d", code, http.StatusBadRequest)
+	}
+}
+
+func TestGetCapsuleRevealsOpenedContent(t *testing.T) {
+	s := newTestServer(t)
+	c := s.seed(t, "Opened", model.CapsuleStatusSealed)
+	if err := s.capsules.UpdateStatus(context.Background(), c.ID, model.CapsuleStatusSealed, model.CapsuleStatusOpened); err != nil {
+		t.Fatal(err)
+	}
+
+	var details CapsuleDetails
+	if code := s.do(t, "participant", "GET", "/api/capsule/"+c.ID.Hex(), nil, &details); code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", code, http.StatusOK)
+	}
+
+	if !details.IsOpened || len(details.ContentItems) != 1 {
+		t.Errorf("opened capsule shows %d content items, want 1", len(details.ContentItems))
+	}
+}
+
+func TestGetCapsuleGeofence(t *testing.T) {
+	s := newTestServer(t)
+	c := &model.Capsule{
+		ID:                primitive.NewObjectID(),
+		Title:             "Fenced",
+		Description:       "A capsule for the tests",
+		Creator:           "creator",
+		Status:            model.CapsuleStatusSealed,
+		ParticipantEmails: []string{testEmails["participant"]},
+		ScheduledOpenDate: time.Now().Add(-time.Hour),
+		ContentItems:      []model.ContentItem{testMessage("Hello")},
+		// 500 m around the Eiffel Tower.
+		Geofence:  &model.Geofence{Latitude: 48.8584, Longitude: 2.2945, RadiusMeters: 500},
+		CreatedAt: time.Now(),
+	}
+	ctx := context.Background()
+	if err := s.capsules.Create(ctx, c); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.capsules.UpdateStatus(ctx, c.ID, model.CapsuleStatusSealed, model.CapsuleStatusOpened); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		user      string
+		query     string
+		wantItems int
+	}{
+		{"no location", "participant", "", 0},
+		{"outside", "participant", "?lat=48.8606&lng=2.3376", 0},
+		{"invalid location", "participant", "?lat=north&lng=2.2945", 0},
+		{"inside", "participant", "?lat=48.8556&lng=2.2986", 1},
+		{"inside again", "participant", "?lat=48.8584&lng=2.2945", 1},
+		{"creator anywhere", "creator", "", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var details CapsuleDetails
+			if code := s.do(t, tt.user, "GET", "/api/capsule/"+c.ID.Hex()+tt.query, nil, &details); code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", code, http.StatusOK)
+			}
+			if len(details.ContentItems) != tt.wantItems || details.OutsideGeofence != (tt.wantItems == 0) {
+				t.Errorf("got %d items, outside_geofence = %v, want %d items", len(details.ContentItems), details.OutsideGeofence, tt.wantItems)
+			}
+		})
+	}
+
+	var details CapsuleDetails
+	s.do(t, "creator", "GET", "/api/capsule/"+c.ID.Hex(), nil, &details)
+	if len(details.LocationUnlocks) != 1 || details.LocationUnlocks[0].UserID != "participant" {
+		t.Errorf("location unlocks = %+v, want the first unlock of the participant", details.LocationUnlocks)
+	}
+}
+
+func TestGetAllCapsules(t *testing.T) {
+	s := newTestServer(t)
+	for _, title := range []string{"Birthday", "Wedding", "Retrospective"} {
+		s.seed(t, title, model.CapsuleStatusSealed)
+	}
+	s.seed(t, "Unfinished", model.CapsuleStatusDraft)
+
+	tests := []struct {
+		name      string
+		user      string
+		query     string
+		wantTotal int64
+		wantCount int
+	}{
+		{"creator sees all", "creator", "", 4, 4},
+		{"participant sees drafts", "participant", "", 4, 4},
+		{"stranger sees none", "stranger", "", 0, 0},
+		{"search", "creator", "?searchQuery=wed", 1, 1},
+		{"pagination", "creator", "?page=2&limit=3", 4, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var page PaginationResponse
+			if code := s.do(t, tt.user, "GET", "/api/capsule"+tt.query, nil, &page); code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", code, http.StatusOK)
+			}
+
+			if page.TotalCount != tt.wantTotal || len(page.Data) != tt.wantCount {
+				t.Errorf("got %d of %d capsules, want %d of %d", len(page.Data), page.TotalCount, tt.wantCount, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestDeleteCapsule(t *testing.T) {
+	s := newTestServer(t)
+	c := s.seed(t, "Doomed", model.CapsuleStatusSealed)
+	path := "/api/capsule/" + c.ID.Hex()
+
+	for _, user := range []string{"participant", "stranger"} {
+		if code := s.do(t, user, "DELETE", path, nil, nil); code != http.StatusNotFound {
+			t.Errorf("delete by %s: status = %d, want %d", user, code, http.StatusNotFound)
+		}
+	}
+
+	if code := s.do(t, "creator", "DELETE", path, nil, nil); code != http.StatusOK {
+		t.Fatalf("delete by creator: status = %d, want %d", code, http.StatusOK)
+	}
+
+	if _, err := s.capsules.GetForUser(context.Background(), c.ID, "creator", ""); err != store.ErrNotFound {
+		t.Errorf("capsule still exists after delete: %v", err)
+	}
+
+	if code := s.do(t, "creator", "DELETE", path, nil, nil); code != http.StatusNotFound {
+		t.Errorf("second delete: status = %d, want %d", code, http.StatusNotFound)
+	}
+}
+
+func TestUpdateCapsule(t *testing.T) {
+	s := newTestServer(t)
+	c := s.seed(t, "Sealed", model.CapsuleStatusSealed)
+	path := "/api/capsule/" + c.ID.Hex()
+
+	if code := s.do(t, "creator", "PATCH", path, map[string]any{"title": "Renamed"}, nil); code != http.StatusOK {
+		t.Fatalf("edit: status = %d, want %d", code, http.StatusOK)
+	}
+	if got, _ := s.capsules.GetForUser(context.Background(), c.ID, "creator", ""); got.Title != "Renamed" {
+		t.Errorf("title = %q, want Renamed", got.Title)
+	}
+
+	if code := s.do(t, "participant", "PATCH", path, map[string]any{"title": "Mine"}, nil); code != http.StatusNotFound {
+		t.Errorf("edit by participant: status = %d, want %d", code, http.StatusNotFound)
+	}
+
+	body := map[string]any{"scheduled_open_date": time.Now().Add(48 * time.Hour)}
+	if code := s.do(t, "creator", "PATCH", path, body, nil); code != http.StatusConflict {
+		t.Errorf("moving the open date of a sealed capsule: status = %d, want %d", code, http.StatusConflict)
+	}
+}
+
+func TestUpdateCapsuleEditCutoff(t *testing.T) {
+	s := newTestServer(t)
+	c := s.seed(t, "Soon", model.CapsuleStatusSealed)
+	c.ScheduledOpenDate = time.Now().Add(config.CapsuleEditCutoff / 2)
+	if err := s.capsules.Update(context.Background(), c); err != nil {
+		t.Fatal(err)
+	}
+
+	if code := s.do(t, "creator", "PATCH", "/api/capsule/"+c.ID.Hex(), map[string]any{"title": "Too late"}, nil); code != http.StatusConflict {
+		t.Errorf("status = %d, want %d", code, http.StatusConflict)
+	}
+}
+
+func TestUpdateDraftKeepsContributions(t *testing.T) {
+	s := newTestServer(t)
+	c := s.seed(t, "Draft", model.CapsuleStatusDraft)
+	contribution := testMessage("From a participant")
+	contribution.ContributedBy = testEmails["participant"]
+	if err := s.capsules.AddContentItems(context.Background(), c.ID, []model.ContentItem{contribution}, MAX_CONTENT_ITEMS, MAX_CONTRIBUTIONS_PER_PARTICIPANT); err != nil {
+		t.Fatal(err)
+	}
+	path := "/api/capsule/" + c.ID.Hex()
+
+	// The creator edits the items they got back and sends them again.
+	var details CapsuleDetails
+	if code := s.do(t, "creator", "GET", path, nil, &details); code != http.StatusOK {
+		t.Fatalf("get: status = %d, want %d", code, http.StatusOK)
+	}
+	if len(details.ContentItems) != 1 || len(details.Contributions) != 1 {
+		t.Fatalf("got %d items and %d contributions, want 1 and 1", len(details.ContentItems), len(details.Contributions))
+	}
+	items := append(details.ContentItems, testMessage("Another one"))
+	if code := s.do(t, "creator", "PATCH", path, map[string]any{"content_items": items}, nil); code != http.StatusOK {
+		t.Fatalf("edit: status = %d, want %d", code, http.StatusOK)
+	}
+
+	got, err := s.capsules.GetForUser(context.Background(), c.ID, "creator", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	contributions := 0
+	for _, item := range got.ContentItems {
+		if item.ContributedBy != "" {
+			contributions++
+		}
+	}
+	if len(got.ContentItems) != 3 || contributions != 1 {
+		t.Errorf("capsule has %d items with %d contributions, want 3 with 1", len(got.ContentItems), contributions)
+	}
+}
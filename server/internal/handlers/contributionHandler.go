@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/pateldivyesh1323/futflare/server/internal/model"
+	"github.com/pateldivyesh1323/futflare/server/internal/store"
+	"github.com/pateldivyesh1323/futflare/server/internal/utils"
+)
+
+const (
+	MAX_CONTRIBUTIONS_PER_PARTICIPANT = 3
+)
+
+type ContributionRequest struct {
+	ContentItems []model.ContentItem `json:"content_items"`
+}
+
+// AddContribution lets a participant append their own content items to a
+// draft capsule. Each item is attributed to the participant's email.
+func (h *Handler) AddContribution(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id := params["id"]
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer")
+	userId, fullId, err := utils.GetIDFromToken(token)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusForbidden, "Unauthorized", nil)
+		return
+	}
+
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, "Invalid capsule ID", nil)
+		return
+	}
+
+	var req ContributionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, "Invalid request body", nil)
+		return
+	}
+
+	if len(req.ContentItems) == 0 {
+		utils.SendJSONResponse(w, http.StatusBadRequest, "Please add at least one content item", nil)
+		return
+	}
+
+	if status, err := validateContentItems(req.ContentItems); err != nil {
+		utils.SendJSONResponse(w, status, err.Error(), nil)
+		return
+	}
+
+	for _, item := range req.ContentItems {
+		if item.Type == model.ContentTypePrediction || item.Type == model.ContentTypeGoals {
+			utils.SendJSONResponse(w, http.StatusBadRequest, fmt.Sprintf("Only the creator can add %s items", item.Type), nil)
+			return
+		}
+	}
+
+	userDetails, err := h.GetUser(fullId)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusInternalServerError, "Internal server error", nil)
+		return
+	}
+
+	capsule, err := h.Capsules.GetForContributor(r.Context(), objectId, userId, userDetails.Email)
+	if err != nil {
+		if err == store.ErrNotFound {
+			utils.SendJSONResponse(w, http.StatusNotFound, "Capsule not found", nil)
+		} else {
+			utils.SendJSONResponse(w, http.StatusInternalServerError, "Internal server error", nil)
+		}
+		return
+	}
+
+	if capsule.Creator == userId {
+		utils.SendJSONResponse(w, http.StatusBadRequest, "Creators add content by editing the capsule", nil)
+		return
+	}
+
+	if capsule.Status != model.CapsuleStatusDraft {
+		utils.SendJSONResponse(w, http.StatusConflict, "Contributions are closed once the capsule is sealed", nil)
+		return
+	}
+
+	contributed := 0
+	for _, item := range capsule.ContentItems {
+		if item.ContributedBy == userDetails.Email {
+			contributed++
+		}
+	}
+
+	if contributed+len(req.ContentItems) > MAX_CONTRIBUTIONS_PER_PARTICIPANT {
+		utils.SendJSONResponse(w, http.StatusForbidden, fmt.Sprintf("You can contribute maximum %d content items!", MAX_CONTRIBUTIONS_PER_PARTICIPANT), nil)
+		return
+	}
+
+	if len(capsule.ContentItems)+len(req.ContentItems) > MAX_CONTENT_ITEMS {
+		utils.SendJSONResponse(w, http.StatusForbidden, fmt.Sprintf("Capsule can hold maximum %d content items!", MAX_CONTENT_ITEMS), nil)
+		return
+	}
+
+	if status, err := h.verifyUploads(r.Context(), userId, req.ContentItems, nil); err != nil {
+		utils.SendJSONResponse(w, status, err.Error(), nil)
+		return
+	}
+
+	for i := range req.ContentItems {
+		req.ContentItems[i].ContributedBy = userDetails.Email
+	}
+
+	if err := prepareContentItems(req.ContentItems, nil); err != nil {
+		utils.SendJSONResponse(w, http.StatusInternalServerError, "Internal server error", nil)
+		return
+	}
+
+	err = h.Capsules.AddContentItems(r.Context(), objectId, req.ContentItems, MAX_CONTENT_ITEMS, MAX_CONTRIBUTIONS_PER_PARTICIPANT)
+	if err != nil {
+		if err == store.ErrNotFound {
+			utils.SendJSONResponse(w, http.StatusConflict, "Capsule changed while adding your contribution, please try again", nil)
+		} else {
+			utils.SendJSONResponse(w, http.StatusInternalServerError, "Internal server error", nil)
+		}
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusCreated, "Successfully added contribution", nil)
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/pateldivyesh1323/futflare/server/internal/model"
+)
+
+func TestAddContribution(t *testing.T) {
+	s := newTestServer(t)
+	draft := s.seed(t, "Draft", model.CapsuleStatusDraft)
+	path := "/api/capsule/" + draft.ID.Hex() + "/contributions"
+
+	contribution := func(text string) map[string]any {
+		return map[string]any{
+			"content_items": []map[string]any{
+				{"type": "message", "content": map[string]any{"text": text}},
+			},
+		}
+	}
+
+	for i := 1; i <= MAX_CONTRIBUTIONS_PER_PARTICIPANT; i++ {
+		if code := s.do(t, "participant", "POST", path, contribution(fmt.Sprint("Wish ", i)), nil); code != http.StatusCreated {
+			t.Fatalf("contribution %d: status = %d, want %d", i, code, http.StatusCreated)
+		}
+	}
+
+	if code := s.do(t, "participant", "POST", path, contribution("One too many"), nil); code != http.StatusForbidden {
+		t.Errorf("contribution past the limit: status = %d, want %d", code, http.StatusForbidden)
+	}
+	if code := s.do(t, "stranger", "POST", path, contribution("Hi"), nil); code != http.StatusNotFound {
+		t.Errorf("contribution by stranger: status = %d, want %d", code, http.StatusNotFound)
+	}
+
+	// The participant sees the draft but not its content.
+	var details CapsuleDetails
+	if code := s.do(t, "participant", "GET", "/api/capsule/"+draft.ID.Hex(), nil, &details); code != http.StatusOK {
+		t.Fatalf("get draft: status = %d, want %d", code, http.StatusOK)
+	}
+	if details.Status != model.CapsuleStatusDraft || len(details.ContentItems) != 0 {
+		t.Errorf("participant got a %s capsule with %d content items, want a draft without content", details.Status, len(details.ContentItems))
+	}
+
+	c, err := s.capsules.GetForUser(context.Background(), draft.ID, "creator", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.ContentItems) != 1+MAX_CONTRIBUTIONS_PER_PARTICIPANT {
+		t.Errorf("capsule has %d content items, want %d", len(c.ContentItems), 1+MAX_CONTRIBUTIONS_PER_PARTICIPANT)
+	}
+}
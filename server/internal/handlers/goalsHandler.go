@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/pateldivyesh1323/futflare/server/internal/model"
+	"github.com/pateldivyesh1323/futflare/server/internal/store"
+	"github.com/pateldivyesh1323/futflare/server/internal/utils"
+)
+
+const (
+	MAX_GOAL_NOTES_LENGTH = 1000
+)
+
+type GoalAssessmentRequest struct {
+	Status model.GoalStatus `json:"status"`
+	Notes  string           `json:"notes"`
+}
+
+// AssessGoal records how the calling member did on a goal once the capsule
+// has opened. Members can change their assessment at any time.
+func (h *Handler) AssessGoal(w http.ResponseWriter, r *http.Request) {
+	goalId, err := primitive.ObjectIDFromHex(mux.Vars(r)["goalId"])
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, "Invalid goal ID", nil)
+		return
+	}
+
+	var req GoalAssessmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, "Invalid request body", nil)
+		return
+	}
+
+	if err := req.Status.Validate(); err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	if len(req.Notes) > MAX_GOAL_NOTES_LENGTH {
+		utils.SendJSONResponse(w, http.StatusBadRequest, "Notes are too long", nil)
+		return
+	}
+
+	capsule, email, ok := h.getMemberCapsule(w, r, false)
+	if !ok {
+		return
+	}
+
+	item, goal := model.FindGoal(capsule.ContentItems, goalId)
+	if goal == nil {
+		utils.SendJSONResponse(w, http.StatusNotFound, "Goal not found", nil)
+		return
+	}
+
+	if !capsule.IsOpened || !item.IsRevealed(time.Now()) {
+		utils.SendJSONResponse(w, http.StatusConflict, "Goals can only be assessed once the capsule has opened", nil)
+		return
+	}
+
+	assessment := model.GoalAssessment{
+		ParticipantEmail: email,
+		Status:           req.Status,
+		Notes:            req.Notes,
+		AssessedAt:       time.Now(),
+	}
+
+	err = h.Capsules.SetGoalAssessment(r.Context(), capsule.ID, goalId, assessment)
+	if err != nil {
+		if err == store.ErrNotFound {
+			utils.SendJSONResponse(w, http.StatusConflict, "Capsule changed while saving your assessment, please try again", nil)
+		} else {
+			utils.SendJSONResponse(w, http.StatusInternalServerError, "Internal server error", nil)
+		}
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, "Successfully assessed goal", assessment)
+}
+
+// prepareGoals gives every goal a new ID. Goals are only assessed after
+// opening, so assessments sent by the client are dropped.
+func prepareGoals(items []model.ContentItem) {
+	for i := range items {
+		goals, ok := items[i].Content.(*model.GoalsContent)
+		if !ok {
+			continue
+		}
+
+		for j := range goals.Goals {
+			goals.Goals[j].ID = primitive.NewObjectID()
+			goals.Goals[j].Assessments = []model.GoalAssessment{}
+		}
+	}
+}
@@ -1,6 +1,56 @@
 package handlers
 
-import "net/http"
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pateldivyesh1323/futflare/server/internal/preview"
+	"github.com/pateldivyesh1323/futflare/server/internal/ratelimit"
+	"github.com/pateldivyesh1323/futflare/server/internal/scheduler"
+	"github.com/pateldivyesh1323/futflare/server/internal/storage"
+	"github.com/pateldivyesh1323/futflare/server/internal/store"
+)
+
+const (
+	MAX_UNLOCK_ATTEMPTS   = 5
+	UNLOCK_ATTEMPT_WINDOW = 15 * time.Minute
+)
+
+// Handler holds the dependencies shared by the API handlers.
+type Handler struct {
+	Capsules store.CapsuleStore
+	// Uploads tracks multipart uploads.
+	Uploads store.UploadSessionStore
+	// Files stores uploaded media.
+	Files storage.ObjectStore
+	// GetUser resolves an Auth0 user id to the user's profile. Tests can
+	// replace it to avoid calling the Auth0 management API.
+	GetUser func(userId string) (UserDetails, error)
+	// UnlockAttempts limits failed passphrase attempts per user and capsule.
+	UnlockAttempts *ratelimit.Limiter
+	// Previews fetches the link previews of sealed capsules. Tests can
+	// replace it with a preview.StubFetcher.
+	Previews preview.Fetcher
+	// LinkPreviews captures the link previews of sealed capsules with
+	// Previews.
+	LinkPreviews *scheduler.LinkPreviewQueue
+	// MediaDeletions receives the uploads of deleted capsules.
+	MediaDeletions *scheduler.MediaDeletionQueue
+	// tusUploads holds the IDs of the tus uploads a request is writing to.
+	tusUploads sync.Map
+}
+
+func NewHandler(capsules store.CapsuleStore, uploads store.UploadSessionStore, files storage.ObjectStore) *Handler {
+	return &Handler{
+		Capsules:       capsules,
+		Uploads:        uploads,
+		Files:          files,
+		GetUser:        getUserById,
+		UnlockAttempts: ratelimit.New(MAX_UNLOCK_ATTEMPTS, UNLOCK_ATTEMPT_WINDOW),
+		Previews:       preview.NewHTTPFetcher(preview.DefaultTimeout, preview.DefaultMaxBytes),
+	}
+}
 
 func HomeHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("<h1>Welcome to Futflare Backend</h1>"))
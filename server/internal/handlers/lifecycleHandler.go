@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/pateldivyesh1323/futflare/server/internal/config"
+	"github.com/pateldivyesh1323/futflare/server/internal/model"
+	"github.com/pateldivyesh1323/futflare/server/internal/store"
+	"github.com/pateldivyesh1323/futflare/server/internal/utils"
+)
+
+// SealCapsule turns a draft into a sealed capsule once it is complete. Sealed
+// capsules are opened by the scheduler on their open date.
+func (h *Handler) SealCapsule(w http.ResponseWriter, r *http.Request) {
+	capsule, ok := h.getOwnCapsule(w, r)
+	if !ok {
+		return
+	}
+
+	if !capsule.Status.CanTransitionTo(model.CapsuleStatusSealed) {
+		utils.SendJSONResponse(w, http.StatusConflict, "Only draft capsules can be sealed", nil)
+		return
+	}
+
+	if status, err := validateCapsule(capsule); err != nil {
+		utils.SendJSONResponse(w, status, err.Error(), nil)
+		return
+	}
+
+	if status, err := validateContentItems(capsule.ContentItems); err != nil {
+		utils.SendJSONResponse(w, status, err.Error(), nil)
+		return
+	}
+
+	if capsule.DeadMansSwitch != nil {
+		capsule.DeadMansSwitch.CheckIn(time.Now(), config.CheckInReminderLead)
+
+		err := h.Capsules.SetDeadMansSwitch(r.Context(), capsule.ID, capsule.DeadMansSwitch)
+		if err != nil {
+			if err == store.ErrNotFound {
+				utils.SendJSONResponse(w, http.StatusConflict, "Capsule changed while it was being updated, please try again", nil)
+			} else {
+				utils.SendJSONResponse(w, http.StatusInternalServerError, "Internal server error", nil)
+			}
+			return
+		}
+	}
+
+	h.transitionCapsule(w, r, capsule, model.CapsuleStatusSealed, "Successfully sealed capsule")
+}
+
+func (h *Handler) ArchiveCapsule(w http.ResponseWriter, r *http.Request) {
+	capsule, ok := h.getOwnCapsule(w, r)
+	if !ok {
+		return
+	}
+
+	if !capsule.Status.CanTransitionTo(model.CapsuleStatusArchived) {
+		utils.SendJSONResponse(w, http.StatusConflict, "Capsule is already archived", nil)
+		return
+	}
+
+	h.transitionCapsule(w, r, capsule, model.CapsuleStatusArchived, "Successfully archived capsule")
+}
+
+func (h *Handler) transitionCapsule(w http.ResponseWriter, r *http.Request, capsule *model.Capsule, to model.CapsuleStatus, message string) {
+	err := h.Capsules.UpdateStatus(r.Context(), capsule.ID, capsule.Status, to)
+	if err != nil {
+		if err == store.ErrNotFound {
+			utils.SendJSONResponse(w, http.StatusConflict, "Capsule changed while it was being updated, please try again", nil)
+		} else {
+			utils.SendJSONResponse(w, http.StatusInternalServerError, "Internal server error", nil)
+		}
+		return
+	}
+
+	capsule.Status = to
+	if to == model.CapsuleStatusSealed {
+		h.queueLinkPreviews(capsule)
+	}
+	utils.SendJSONResponse(w, http.StatusOK, message, newCapsuleSummary(*capsule))
+}
+
+// CheckIn restarts the dead man's switch of a sealed capsule. Only the creator
+// can check in.
+func (h *Handler) CheckIn(w http.ResponseWriter, r *http.Request) {
+	capsule, ok := h.getOwnCapsule(w, r)
+	if !ok {
+		return
+	}
+
+	if capsule.DeadMansSwitch == nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, "Capsule does not have a dead man's switch", nil)
+		return
+	}
+
+	if capsule.Status != model.CapsuleStatusSealed {
+		utils.SendJSONResponse(w, http.StatusConflict, "Only sealed capsules need check-ins", nil)
+		return
+	}
+
+	capsule.DeadMansSwitch.CheckIn(time.Now(), config.CheckInReminderLead)
+
+	err := h.Capsules.SetDeadMansSwitch(r.Context(), capsule.ID, capsule.DeadMansSwitch)
+	if err != nil {
+		if err == store.ErrNotFound {
+			utils.SendJSONResponse(w, http.StatusConflict, "Capsule has already opened", nil)
+		} else {
+			utils.SendJSONResponse(w, http.StatusInternalServerError, "Internal server error", nil)
+		}
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, "Successfully checked in", capsule.DeadMansSwitch)
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pateldivyesh1323/futflare/server/internal/model"
+)
+
+func TestCheckIn(t *testing.T) {
+	s := newTestServer(t)
+	ctx := context.Background()
+
+	c := s.seed(t, "Switch", model.CapsuleStatusSealed)
+	sw := &model.DeadMansSwitch{CheckInDays: 7, GraceDays: 1}
+	sw.CheckIn(time.Now().AddDate(0, 0, -6), 0)
+	if err := s.capsules.SetDeadMansSwitch(ctx, c.ID, sw); err != nil {
+		t.Fatal(err)
+	}
+	plain := s.seed(t, "Plain", model.CapsuleStatusSealed)
+
+	tests := []struct {
+		name string
+		user string
+		id   string
+		want int
+	}{
+		{"participant", "participant", c.ID.Hex(), http.StatusNotFound},
+		{"no switch", "creator", plain.ID.Hex(), http.StatusBadRequest},
+		{"creator", "creator", c.ID.Hex(), http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := s.do(t, tt.user, "POST", "/api/capsule/"+tt.id+"/check-in", nil, nil); code != tt.want {
+				t.Errorf("status = %d, want %d", code, tt.want)
+			}
+		})
+	}
+
+	got, err := s.capsules.GetForUser(ctx, c.ID, "creator", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(got.DeadMansSwitch.LastCheckIn) > time.Minute {
+		t.Errorf("last check-in = %v, want it restarted", got.DeadMansSwitch.LastCheckIn)
+	}
+
+	if err := s.capsules.UpdateStatus(ctx, c.ID, model.CapsuleStatusSealed, model.CapsuleStatusOpened); err != nil {
+		t.Fatal(err)
+	}
+	if code := s.do(t, "creator", "POST", "/api/capsule/"+c.ID.Hex()+"/check-in", nil, nil); code != http.StatusConflict {
+		t.Errorf("opened capsule: status = %d, want %d", code, http.StatusConflict)
+	}
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"github.com/pateldivyesh1323/futflare/server/internal/model"
+)
+
+// prepareLinks drops previews sent by the client. Links that already exist
+// in existing keep their captured preview.
+func prepareLinks(items []model.ContentItem, existing []model.ContentItem) {
+	captured := map[string]*model.LinkPreview{}
+	for _, item := range existing {
+		if link, ok := item.Content.(*model.LinkContent); ok && link.Preview != nil {
+			captured[link.URL] = link.Preview
+		}
+	}
+
+	for i := range items {
+		if link, ok := items[i].Content.(*model.LinkContent); ok {
+			link.Preview = captured[link.URL]
+		}
+	}
+}
+
+// queueLinkPreviews schedules the capture of the previews of the links of a
+// sealed capsule that have none yet.
+func (h *Handler) queueLinkPreviews(c *model.Capsule) {
+	if h.LinkPreviews != nil {
+		h.LinkPreviews.Enqueue(c.ID, c.ContentItems)
+	}
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/pateldivyesh1323/futflare/server/internal/store"
+	"github.com/pateldivyesh1323/futflare/server/internal/utils"
+)
+
+type UnlockRequest struct {
+	Passphrase string `json:"passphrase"`
+}
+
+// UnlockCapsule checks a passphrase against a locked capsule. Once it matches
+// the user can read the capsule's content. Failed attempts are limited per
+// user and capsule.
+func (h *Handler) UnlockCapsule(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id := params["id"]
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer")
+	userID, fullId, err := utils.GetIDFromToken(token)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusForbidden, "Unauthorized", nil)
+		return
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, "Invalid capsule ID", nil)
+		return
+	}
+
+	var req UnlockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, "Invalid request body", nil)
+		return
+	}
+
+	userDetails, err := h.GetUser(fullId)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusInternalServerError, "Internal server error", nil)
+		return
+	}
+
+	capsule, err := h.Capsules.GetForUser(r.Context(), objectID, userID, userDetails.Email)
+	if err != nil {
+		if err == store.ErrNotFound {
+			utils.SendJSONResponse(w, http.StatusNotFound, "Capsule not found", nil)
+		} else {
+			utils.SendJSONResponse(w, http.StatusInternalServerError, "Internal server error", nil)
+		}
+		return
+	}
+
+	if capsule.Lock == nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, "Capsule is not locked", nil)
+		return
+	}
+
+	if !capsule.IsOpened {
+		utils.SendJSONResponse(w, http.StatusConflict, "Capsule can only be unlocked once it has opened", nil)
+		return
+	}
+
+	// Every guess counts until one is correct, so concurrent wrong guesses
+	// cannot get past the limit.
+	attemptKey := userID + ":" + id
+	if !h.UnlockAttempts.Attempt(attemptKey) {
+		utils.SendJSONResponse(w, http.StatusTooManyRequests, "Too many failed attempts, please try again later", nil)
+		return
+	}
+
+	if !capsule.Lock.Matches(req.Passphrase) {
+		utils.SendJSONResponse(w, http.StatusForbidden, "Incorrect passphrase", nil)
+		return
+	}
+
+	h.UnlockAttempts.Reset(attemptKey)
+
+	err = h.Capsules.AddUnlockedBy(r.Context(), objectID, userID)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusInternalServerError, "Internal server error", nil)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, "Successfully unlocked capsule", nil)
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/pateldivyesh1323/futflare/server/internal/model"
+)
+
+// seedLocked stores an opened capsule locked with passphrase.
+func (s *testServer) seedLocked(t *testing.T, passphrase string) *model.Capsule {
+	t.Helper()
+
+	lock := &model.PassphraseLock{Passphrase: passphrase, Hint: "A classic"}
+	if err := lock.HashPassphrase(); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &model.Capsule{
+		ID:                primitive.NewObjectID(),
+		Title:             "Locked",
+		Description:       "A capsule for the tests",
+		Creator:           "creator",
+		Status:            model.CapsuleStatusSealed,
+		ParticipantEmails: []string{testEmails["participant"]},
+		ScheduledOpenDate: time.Now().Add(-time.Hour),
+		ContentItems:      []model.ContentItem{testMessage("Hello")},
+		Lock:              lock,
+		CreatedAt:         time.Now(),
+	}
+	ctx := context.Background()
+	if err := s.capsules.Create(ctx, c); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.capsules.UpdateStatus(ctx, c.ID, model.CapsuleStatusSealed, model.CapsuleStatusOpened); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestUnlockCapsule(t *testing.T) {
+	s := newTestServer(t)
+	c := s.seedLocked(t, "Open Sesame")
+	path := "/api/capsule/" + c.ID.Hex()
+
+	var details CapsuleDetails
+	if code := s.do(t, "participant", "GET", path, nil, &details); code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", code, http.StatusOK)
+	}
+	if !details.IsLocked || len(details.ContentItems) != 0 {
+		t.Errorf("locked capsule: is_locked = %v with %d items, want it locked without items", details.IsLocked, len(details.ContentItems))
+	}
+
+	if code := s.do(t, "participant", "POST", path+"/unlock", UnlockRequest{Passphrase: "wrong"}, nil); code != http.StatusForbidden {
+		t.Errorf("wrong passphrase: status = %d, want %d", code, http.StatusForbidden)
+	}
+	if code := s.do(t, "stranger", "POST", path+"/unlock", UnlockRequest{Passphrase: "open sesame"}, nil); code != http.StatusNotFound {
+		t.Errorf("stranger: status = %d, want %d", code, http.StatusNotFound)
+	}
+	// Answers are case and whitespace insensitive.
+	if code := s.do(t, "participant", "POST", path+"/unlock", UnlockRequest{Passphrase: "  open   SESAME "}, nil); code != http.StatusOK {
+		t.Fatalf("right passphrase: status = %d, want %d", code, http.StatusOK)
+	}
+
+	details = CapsuleDetails{}
+	s.do(t, "participant", "GET", path, nil, &details)
+	if details.IsLocked || len(details.ContentItems) != 1 {
+		t.Errorf("unlocked capsule: is_locked = %v with %d items, want it unlocked with 1 item", details.IsLocked, len(details.ContentItems))
+	}
+}
+
+func TestUnlockCapsuleLocksOut(t *testing.T) {
+	s := newTestServer(t)
+	c := s.seedLocked(t, "Open Sesame")
+	path := "/api/capsule/" + c.ID.Hex() + "/unlock"
+
+	for i := 0; i < MAX_UNLOCK_ATTEMPTS; i++ {
+		if code := s.do(t, "participant", "POST", path, UnlockRequest{Passphrase: "wrong"}, nil); code != http.StatusForbidden {
+			t.Fatalf("attempt %d: status = %d, want %d", i+1, code, http.StatusForbidden)
+		}
+	}
+
+	if code := s.do(t, "participant", "POST", path, UnlockRequest{Passphrase: "open sesame"}, nil); code != http.StatusTooManyRequests {
+		t.Errorf("after %d failed attempts: status = %d, want %d", MAX_UNLOCK_ATTEMPTS, code, http.StatusTooManyRequests)
+	}
+	// The limit is per user, the creator can still try.
+	if code := s.do(t, "creator", "POST", path, UnlockRequest{Passphrase: "open sesame"}, nil); code != http.StatusOK {
+		t.Errorf("creator: status = %d, want %d", code, http.StatusOK)
+	}
+}
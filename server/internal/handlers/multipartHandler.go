@@ -0,0 +1,344 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/pateldivyesh1323/futflare/server/internal/model"
+	"github.com/pateldivyesh1323/futflare/server/internal/storage"
+	"github.com/pateldivyesh1323/futflare/server/internal/store"
+	"github.com/pateldivyesh1323/futflare/server/internal/utils"
+)
+
+const (
+	// MULTIPART_PART_SIZE is the size of every part but the last. S3 requires
+	// at least 5 MB.
+	MULTIPART_PART_SIZE = 10 << 20
+	// MAX_MULTIPART_PARTS is the most parts S3 joins into one object.
+	MAX_MULTIPART_PARTS   = 10000
+	MAX_PARTS_PER_REQUEST = 100
+	UPLOAD_SESSION_EXPIRY = 7 * 24 * time.Hour
+)
+
+// MAX_MULTIPART_UPLOAD_SIZES raises the size limit of MAX_UPLOAD_SIZES for
+// uploads in parts, which need not fit into a single request. A limit must
+// not need more than MAX_MULTIPART_PARTS parts of MULTIPART_PART_SIZE.
+var MAX_MULTIPART_UPLOAD_SIZES = map[model.ContentType]int64{
+	model.ContentTypeVideo: 20 << 30,
+}
+
+// maxUploadSize returns the size limit of uploads of a content type.
+func maxUploadSize(contentType model.ContentType, multipart bool) int64 {
+	if size, ok := MAX_MULTIPART_UPLOAD_SIZES[contentType]; ok && multipart {
+		return size
+	}
+	return MAX_UPLOAD_SIZES[contentType]
+}
+
+type SignPartsRequest struct {
+	// PartNumbers selects the parts to sign, all missing parts when empty.
+	PartNumbers []int32 `json:"part_numbers"`
+}
+
+type SignedPart struct {
+	Number int32  `json:"number"`
+	URL    string `json:"presigned_url"`
+	Size   int64  `json:"size"`
+}
+
+type UploadSessionResponse struct {
+	*model.UploadSession
+	UploadedBytes int64   `json:"uploaded_bytes"`
+	MissingParts  []int32 `json:"missing_parts"`
+}
+
+func newUploadSessionResponse(session *model.UploadSession) UploadSessionResponse {
+	return UploadSessionResponse{
+		UploadSession: session,
+		UploadedBytes: session.UploadedBytes(),
+		MissingParts:  session.MissingParts(),
+	}
+}
+
+// StartMultipartUpload starts an upload in parts. It takes the same request
+// as GeneratePresignedURL and returns a session that the client signs parts
+// for and completes once every part is uploaded.
+func (h *Handler) StartMultipartUpload(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer")
+	userId, _, err := utils.GetIDFromToken(token)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusUnauthorized, err.Error(), nil)
+		return
+	}
+
+	var req PresignedURLRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, "Invalid request body", nil)
+		return
+	}
+
+	if err := validatePresignedURLRequest(req, true); err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	objectKey := newObjectKey(userId, req.FileName)
+
+	uploadID, err := h.Files.CreateMultipart(r.Context(), objectKey, req.FileType)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusInternalServerError, "Failed to start upload", nil)
+		return
+	}
+
+	now := time.Now()
+	session := &model.UploadSession{
+		ID:          primitive.NewObjectID(),
+		UserID:      userId,
+		UploadID:    uploadID,
+		ObjectKey:   objectKey,
+		ContentType: model.ContentType(req.ContentType),
+		FileName:    req.FileName,
+		FileType:    req.FileType,
+		FileSize:    req.FileSize,
+		PartSize:    MULTIPART_PART_SIZE,
+		PartCount:   int32((req.FileSize + MULTIPART_PART_SIZE - 1) / MULTIPART_PART_SIZE),
+		Parts:       []model.UploadPart{},
+		Status:      model.UploadSessionUploading,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		ExpiresAt:   now.Add(UPLOAD_SESSION_EXPIRY),
+	}
+
+	if err := h.Uploads.CreateUploadSession(r.Context(), session); err != nil {
+		if err := h.Files.AbortMultipart(context.Background(), objectKey, uploadID); err != nil {
+			log.Printf("Error aborting upload %s: %v", objectKey, err)
+		}
+		utils.SendJSONResponse(w, http.StatusInternalServerError, "Internal server error", nil)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusCreated, "Upload started", newUploadSessionResponse(session))
+}
+
+// GetMultipartUpload returns the progress of an upload, so a client can
+// resume it with the missing parts.
+func (h *Handler) GetMultipartUpload(w http.ResponseWriter, r *http.Request) {
+	session, ok := h.getUploadSession(w, r)
+	if !ok {
+		return
+	}
+
+	if session.Status == model.UploadSessionUploading && time.Now().Before(session.ExpiresAt) {
+		if !h.syncUploadParts(w, r, session) {
+			return
+		}
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, "Successfully fetched upload", newUploadSessionResponse(session))
+}
+
+// SignMultipartParts returns upload URLs for parts of an upload.
+func (h *Handler) SignMultipartParts(w http.ResponseWriter, r *http.Request) {
+	var req SignPartsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, "Invalid request body", nil)
+		return
+	}
+
+	session, ok := h.getUploadingSession(w, r)
+	if !ok {
+		return
+	}
+
+	numbers := req.PartNumbers
+	if len(numbers) == 0 {
+		if !h.syncUploadParts(w, r, session) {
+			return
+		}
+		numbers = session.MissingParts()
+		if len(numbers) > MAX_PARTS_PER_REQUEST {
+			numbers = numbers[:MAX_PARTS_PER_REQUEST]
+		}
+	}
+
+	if len(numbers) > MAX_PARTS_PER_REQUEST {
+		utils.SendJSONResponse(w, http.StatusBadRequest, "Too many parts requested", nil)
+		return
+	}
+
+	parts := make([]SignedPart, 0, len(numbers))
+	for _, number := range numbers {
+		if number < 1 || number > session.PartCount {
+			utils.SendJSONResponse(w, http.StatusBadRequest, "Invalid part number", nil)
+			return
+		}
+
+		size := session.PartSizeOf(number)
+		url, err := h.Files.PresignPart(r.Context(), session.ObjectKey, session.UploadID, number, size, PRESIGNED_URL_EXPIRY)
+		if err != nil {
+			utils.SendJSONResponse(w, http.StatusInternalServerError, "Failed to generate pre-signed URL", nil)
+			return
+		}
+
+		parts = append(parts, SignedPart{Number: number, URL: url, Size: size})
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, "Pre-signed URLs generated successfully", parts)
+}
+
+// CompleteMultipartUpload joins the parts of an upload into the object. The
+// object key can then be added to a capsule like any other upload.
+func (h *Handler) CompleteMultipartUpload(w http.ResponseWriter, r *http.Request) {
+	session, ok := h.getUploadingSession(w, r)
+	if !ok {
+		return
+	}
+
+	if !h.syncUploadParts(w, r, session) {
+		return
+	}
+
+	if missing := session.MissingParts(); len(missing) > 0 {
+		utils.SendJSONResponse(w, http.StatusConflict, "Upload is missing parts", map[string][]int32{"missing_parts": missing})
+		return
+	}
+
+	parts := make([]storage.Part, 0, session.PartCount)
+	for _, part := range session.Parts {
+		if part.Number <= session.PartCount {
+			parts = append(parts, storage.Part{Number: part.Number, ETag: part.ETag, Size: part.Size})
+		}
+	}
+
+	if err := h.Files.CompleteMultipart(r.Context(), session.ObjectKey, session.UploadID, parts); err != nil {
+		utils.SendJSONResponse(w, http.StatusInternalServerError, "Failed to complete upload", nil)
+		return
+	}
+
+	err := h.Uploads.UpdateUploadStatus(r.Context(), session.ID, model.UploadSessionUploading, model.UploadSessionCompleted)
+	if err != nil && err != store.ErrNotFound {
+		utils.SendJSONResponse(w, http.StatusInternalServerError, "Internal server error", nil)
+		return
+	}
+	session.Status = model.UploadSessionCompleted
+
+	utils.SendJSONResponse(w, http.StatusOK, "Upload completed", newUploadSessionResponse(session))
+}
+
+// AbortMultipartUpload cancels an upload and discards its parts.
+func (h *Handler) AbortMultipartUpload(w http.ResponseWriter, r *http.Request) {
+	session, ok := h.getUploadSession(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.Uploads.UpdateUploadStatus(r.Context(), session.ID, model.UploadSessionUploading, model.UploadSessionAborted)
+	if err != nil {
+		if err == store.ErrNotFound {
+			utils.SendJSONResponse(w, http.StatusConflict, "Upload is no longer in progress", nil)
+		} else {
+			utils.SendJSONResponse(w, http.StatusInternalServerError, "Internal server error", nil)
+		}
+		return
+	}
+
+	err = h.Files.AbortMultipart(r.Context(), session.ObjectKey, session.UploadID)
+	if err != nil && err != storage.ErrNotFound {
+		log.Printf("Error aborting upload %s: %v", session.ObjectKey, err)
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, "Upload aborted", nil)
+}
+
+// getUploadSession loads the upload session addressed by the request. Users
+// only see their own sessions. It writes the error response itself.
+func (h *Handler) getUploadSession(w http.ResponseWriter, r *http.Request) (*model.UploadSession, bool) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer")
+	userId, _, err := utils.GetIDFromToken(token)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusUnauthorized, err.Error(), nil)
+		return nil, false
+	}
+
+	id, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, "Invalid upload ID", nil)
+		return nil, false
+	}
+
+	session, err := h.Uploads.GetUploadSession(r.Context(), id, userId)
+	if err == nil && session.Tus {
+		// Tus uploads are written by the server, see PatchTusUpload.
+		err = store.ErrNotFound
+	}
+	if err != nil {
+		if err == store.ErrNotFound {
+			utils.SendJSONResponse(w, http.StatusNotFound, "Upload not found", nil)
+		} else {
+			utils.SendJSONResponse(w, http.StatusInternalServerError, "Internal server error", nil)
+		}
+		return nil, false
+	}
+
+	return session, true
+}
+
+// getUploadingSession is getUploadSession for requests that continue an
+// upload, it refuses finished and expired sessions.
+func (h *Handler) getUploadingSession(w http.ResponseWriter, r *http.Request) (*model.UploadSession, bool) {
+	session, ok := h.getUploadSession(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	if session.Status != model.UploadSessionUploading {
+		utils.SendJSONResponse(w, http.StatusConflict, "Upload is no longer in progress", nil)
+		return nil, false
+	}
+
+	if !time.Now().Before(session.ExpiresAt) {
+		utils.SendJSONResponse(w, http.StatusGone, "Upload expired, please start it again", nil)
+		return nil, false
+	}
+
+	return session, true
+}
+
+// syncUploadParts records the parts the object store has received so far in
+// the session. It writes the error response itself.
+func (h *Handler) syncUploadParts(w http.ResponseWriter, r *http.Request, session *model.UploadSession) bool {
+	stored, err := h.Files.ListParts(r.Context(), session.ObjectKey, session.UploadID)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			utils.SendJSONResponse(w, http.StatusGone, "Upload expired, please start it again", nil)
+		} else {
+			utils.SendJSONResponse(w, http.StatusInternalServerError, "Failed to fetch upload progress", nil)
+		}
+		return false
+	}
+
+	parts := make([]model.UploadPart, 0, len(stored))
+	for _, part := range stored {
+		parts = append(parts, model.UploadPart{Number: part.Number, ETag: part.ETag, Size: part.Size})
+	}
+
+	err = h.Uploads.SetUploadParts(r.Context(), session.ID, parts)
+	if err != nil {
+		if err == store.ErrNotFound {
+			utils.SendJSONResponse(w, http.StatusConflict, "Upload is no longer in progress", nil)
+		} else {
+			utils.SendJSONResponse(w, http.StatusInternalServerError, "Internal server error", nil)
+		}
+		return false
+	}
+	session.Parts = parts
+
+	return true
+}
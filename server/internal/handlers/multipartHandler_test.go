@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/pateldivyesh1323/futflare/server/internal/model"
+)
+
+// upload sends body to a URL signed by the object store.
+func (s *testServer) upload(t *testing.T, rawURL string, body []byte) *httptest.ResponseRecorder {
+	t.Helper()
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPut, u.RequestURI(), bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+func startMultipartUpload(t *testing.T, s *testServer, size int64) UploadSessionResponse {
+	t.Helper()
+
+	req := PresignedURLRequest{ContentType: "video", FileName: "clip.mp4", FileType: "video/mp4", FileSize: size}
+	var session UploadSessionResponse
+	if code := s.do(t, "creator", "POST", "/api/uploader/multipart", req, &session); code != http.StatusCreated {
+		t.Fatalf("start: status = %d, want %d", code, http.StatusCreated)
+	}
+	return session
+}
+
+func TestMultipartUpload(t *testing.T) {
+	s := newTestServer(t)
+	body := append([]byte("\x00\x00\x00\x18ftypmp42"), make([]byte, 100)...)
+
+	session := startMultipartUpload(t, s, int64(len(body)))
+	path := "/api/uploader/multipart/" + session.ID.Hex()
+	if session.PartCount != 1 || len(session.MissingParts) != 1 {
+		t.Fatalf("session = %+v, want one missing part", session)
+	}
+	if code := s.do(t, "participant", "GET", path, nil, nil); code != http.StatusNotFound {
+		t.Errorf("other user: status = %d, want %d", code, http.StatusNotFound)
+	}
+
+	if code := s.do(t, "creator", "POST", path+"/complete", nil, nil); code != http.StatusConflict {
+		t.Errorf("complete without parts: status = %d, want %d", code, http.StatusConflict)
+	}
+
+	tests := []struct {
+		name    string
+		numbers []int32
+		want    int
+	}{
+		{"part past the end", []int32{2}, http.StatusBadRequest},
+		{"part zero", []int32{0}, http.StatusBadRequest},
+		{"too many parts", make([]int32, MAX_PARTS_PER_REQUEST+1), http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if code := s.do(t, "creator", "POST", path+"/parts", SignPartsRequest{PartNumbers: tt.numbers}, nil); code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, code, tt.want)
+		}
+	}
+
+	var parts []SignedPart
+	if code := s.do(t, "creator", "POST", path+"/parts", SignPartsRequest{}, &parts); code != http.StatusOK {
+		t.Fatalf("sign: status = %d, want %d", code, http.StatusOK)
+	}
+	if len(parts) != 1 || parts[0].Number != 1 || parts[0].Size != int64(len(body)) {
+		t.Fatalf("signed parts = %+v, want the missing part", parts)
+	}
+	if w := s.upload(t, parts[0].URL, body); w.Code != http.StatusOK {
+		t.Fatalf("part upload: status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	var progress UploadSessionResponse
+	s.do(t, "creator", "GET", path, nil, &progress)
+	if progress.UploadedBytes != int64(len(body)) || len(progress.MissingParts) != 0 {
+		t.Errorf("progress = %d bytes, missing %v, want the whole file", progress.UploadedBytes, progress.MissingParts)
+	}
+
+	var completed UploadSessionResponse
+	if code := s.do(t, "creator", "POST", path+"/complete", nil, &completed); code != http.StatusOK {
+		t.Fatalf("complete: status = %d, want %d", code, http.StatusOK)
+	}
+	if completed.Status != model.UploadSessionCompleted {
+		t.Errorf("status = %s, want %s", completed.Status, model.UploadSessionCompleted)
+	}
+
+	info, err := s.files.Head(context.Background(), session.ObjectKey)
+	if err != nil || info.Size != int64(len(body)) {
+		t.Errorf("object = %+v, %v, want %d bytes", info, err, len(body))
+	}
+
+	if code := s.do(t, "creator", "POST", path+"/complete", nil, nil); code != http.StatusConflict {
+		t.Errorf("complete again: status = %d, want %d", code, http.StatusConflict)
+	}
+	if code := s.do(t, "creator", "DELETE", path, nil, nil); code != http.StatusConflict {
+		t.Errorf("abort after completing: status = %d, want %d", code, http.StatusConflict)
+	}
+}
+
+func TestAbortMultipartUpload(t *testing.T) {
+	s := newTestServer(t)
+	session := startMultipartUpload(t, s, 2*MULTIPART_PART_SIZE+1)
+	path := "/api/uploader/multipart/" + session.ID.Hex()
+
+	if session.PartCount != 3 {
+		t.Errorf("part count = %d, want 3", session.PartCount)
+	}
+
+	if code := s.do(t, "participant", "DELETE", path, nil, nil); code != http.StatusNotFound {
+		t.Errorf("abort by another user: status = %d, want %d", code, http.StatusNotFound)
+	}
+	if code := s.do(t, "creator", "DELETE", path, nil, nil); code != http.StatusOK {
+		t.Fatalf("abort: status = %d, want %d", code, http.StatusOK)
+	}
+	if code := s.do(t, "creator", "POST", path+"/parts", SignPartsRequest{}, nil); code != http.StatusConflict {
+		t.Errorf("sign after aborting: status = %d, want %d", code, http.StatusConflict)
+	}
+}
+
+func TestStartMultipartUploadLimits(t *testing.T) {
+	s := newTestServer(t)
+
+	tests := []struct {
+		name string
+		req  PresignedURLRequest
+	}{
+		{"no size", PresignedURLRequest{ContentType: "video", FileName: "clip.mp4", FileType: "video/mp4"}},
+		{"no name", PresignedURLRequest{ContentType: "video", FileType: "video/mp4", FileSize: 100}},
+		{"too large", PresignedURLRequest{ContentType: "image", FileName: "photo.png", FileType: "image/png", FileSize: MAX_UPLOAD_SIZES[model.ContentTypeImage] + 1}},
+		{"unsupported audio", PresignedURLRequest{ContentType: "audio", FileName: "a.exe", FileType: "application/x-msdownload", FileSize: 100}},
+		{"not an upload", PresignedURLRequest{ContentType: "message", FileName: "a.txt", FileType: "text/plain", FileSize: 100}},
+	}
+
+	for _, tt := range tests {
+		if code := s.do(t, "creator", "POST", "/api/uploader/multipart", tt.req, nil); code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want %d", tt.name, code, http.StatusBadRequest)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/pateldivyesh1323/futflare/server/internal/model"
+	"github.com/pateldivyesh1323/futflare/server/internal/store"
+	"github.com/pateldivyesh1323/futflare/server/internal/utils"
+)
+
+type PredictionAnswerRequest struct {
+	Answer string `json:"answer"`
+}
+
+type PredictionOutcomeRequest struct {
+	ParticipantEmail string `json:"participant_email"`
+	CameTrue         bool   `json:"came_true"`
+}
+
+// AnswerPrediction records the calling member's answer to a prediction. Answers
+// can be changed until the capsule opens.
+func (h *Handler) AnswerPrediction(w http.ResponseWriter, r *http.Request) {
+	var req PredictionAnswerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, "Invalid request body", nil)
+		return
+	}
+
+	capsule, predictionID, email, ok := h.getPrediction(w, r, true)
+	if !ok {
+		return
+	}
+
+	if capsule.Status != model.CapsuleStatusDraft && capsule.Status != model.CapsuleStatusSealed {
+		utils.SendJSONResponse(w, http.StatusConflict, "Predictions can only be answered before the capsule opens", nil)
+		return
+	}
+
+	_, prediction := model.FindPrediction(capsule.ContentItems, predictionID)
+	if err := prediction.ValidateAnswer(req.Answer); err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	answer := model.PredictionAnswer{
+		ParticipantEmail: email,
+		Answer:           req.Answer,
+		AnsweredAt:       time.Now(),
+	}
+
+	err := h.Capsules.SetPredictionAnswer(r.Context(), capsule.ID, predictionID, answer)
+	if err != nil {
+		if err == store.ErrNotFound {
+			utils.SendJSONResponse(w, http.StatusConflict, "Capsule changed while saving your answer, please try again", nil)
+		} else {
+			utils.SendJSONResponse(w, http.StatusInternalServerError, "Internal server error", nil)
+		}
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, "Successfully answered prediction", answer)
+}
+
+// MarkPredictionOutcome lets a member of an opened capsule mark whether a
+// member's answer to a prediction came true.
+func (h *Handler) MarkPredictionOutcome(w http.ResponseWriter, r *http.Request) {
+	var req PredictionOutcomeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, "Invalid request body", nil)
+		return
+	}
+
+	capsule, predictionID, _, ok := h.getPrediction(w, r, false)
+	if !ok {
+		return
+	}
+
+	if !capsule.IsOpened {
+		utils.SendJSONResponse(w, http.StatusConflict, "Predictions can only be resolved once the capsule has opened", nil)
+		return
+	}
+
+	item, prediction := model.FindPrediction(capsule.ContentItems, predictionID)
+	if !item.IsRevealed(time.Now()) {
+		utils.SendJSONResponse(w, http.StatusNotFound, "Prediction not found", nil)
+		return
+	}
+
+	if prediction.AnswerBy(req.ParticipantEmail) == nil {
+		utils.SendJSONResponse(w, http.StatusNotFound, "Answer not found", nil)
+		return
+	}
+
+	err := h.Capsules.SetPredictionOutcome(r.Context(), capsule.ID, predictionID, req.ParticipantEmail, req.CameTrue)
+	if err != nil {
+		if err == store.ErrNotFound {
+			utils.SendJSONResponse(w, http.StatusConflict, "Capsule changed while saving the outcome, please try again", nil)
+		} else {
+			utils.SendJSONResponse(w, http.StatusInternalServerError, "Internal server error", nil)
+		}
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, "Successfully resolved prediction", nil)
+}
+
+// getPrediction loads the capsule of the prediction addressed by the request
+// and returns the caller's email, see getMemberCapsule. It writes the error
+// response itself.
+func (h *Handler) getPrediction(w http.ResponseWriter, r *http.Request, contributing bool) (*model.Capsule, primitive.ObjectID, string, bool) {
+	predictionId, err := primitive.ObjectIDFromHex(mux.Vars(r)["predictionId"])
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, "Invalid prediction ID", nil)
+		return nil, primitive.NilObjectID, "", false
+	}
+
+	capsule, email, ok := h.getMemberCapsule(w, r, contributing)
+	if !ok {
+		return nil, primitive.NilObjectID, "", false
+	}
+
+	if _, prediction := model.FindPrediction(capsule.ContentItems, predictionId); prediction == nil {
+		utils.SendJSONResponse(w, http.StatusNotFound, "Prediction not found", nil)
+		return nil, primitive.NilObjectID, "", false
+	}
+
+	return capsule, predictionId, email, true
+}
+
+// preparePredictions gives new predictions an ID and drops answers sent by
+// the client. Predictions that already exist in existing keep their stored
+// answers.
+func preparePredictions(items []model.ContentItem, existing []model.ContentItem) {
+	seen := map[primitive.ObjectID]bool{}
+	for i := range items {
+		prediction, ok := items[i].Content.(*model.PredictionContent)
+		if !ok {
+			continue
+		}
+
+		prediction.Answers = []model.PredictionAnswer{}
+
+		_, stored := model.FindPrediction(existing, prediction.ID)
+		if stored != nil && !seen[prediction.ID] {
+			prediction.Answers = stored.Answers
+		} else {
+			prediction.ID = primitive.NewObjectID()
+		}
+		seen[prediction.ID] = true
+	}
+}
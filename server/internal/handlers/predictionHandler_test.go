@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/pateldivyesh1323/futflare/server/internal/model"
+)
+
+func TestAnswerPrediction(t *testing.T) {
+	for _, status := range []model.CapsuleStatus{model.CapsuleStatusDraft, model.CapsuleStatusSealed} {
+		t.Run(string(status), func(t *testing.T) {
+			s := newTestServer(t)
+			c := s.seed(t, "Predictions", status)
+			prediction := &model.PredictionContent{
+				ID:       primitive.NewObjectID(),
+				Question: "Who moves abroad first?",
+				Options:  []string{"Alice", "Bob"},
+				Answers:  []model.PredictionAnswer{},
+			}
+			c.ContentItems = append(c.ContentItems, model.ContentItem{Type: model.ContentTypePrediction, Content: prediction})
+			if err := s.capsules.Update(context.Background(), c); err != nil {
+				t.Fatal(err)
+			}
+			path := "/api/capsule/" + c.ID.Hex()
+
+			// The participant finds the question on the capsule itself.
+			var details CapsuleDetails
+			if code := s.do(t, "participant", "GET", path, nil, &details); code != http.StatusOK {
+				t.Fatalf("get capsule: status = %d, want %d", code, http.StatusOK)
+			}
+			if len(details.Predictions) != 1 || details.Predictions[0].Question != prediction.Question {
+				t.Fatalf("predictions = %+v, want the question", details.Predictions)
+			}
+			answerPath := path + "/predictions/" + details.Predictions[0].ID.Hex() + "/answer"
+
+			if code := s.do(t, "participant", "POST", answerPath, map[string]string{"answer": "Carol"}, nil); code != http.StatusBadRequest {
+				t.Errorf("answer outside the options: status = %d, want %d", code, http.StatusBadRequest)
+			}
+			if code := s.do(t, "participant", "POST", answerPath, map[string]string{"answer": "Bob"}, nil); code != http.StatusOK {
+				t.Fatalf("answer: status = %d, want %d", code, http.StatusOK)
+			}
+			if code := s.do(t, "stranger", "POST", answerPath, map[string]string{"answer": "Bob"}, nil); code != http.StatusNotFound {
+				t.Errorf("answer by stranger: status = %d, want %d", code, http.StatusNotFound)
+			}
+
+			if code := s.do(t, "participant", "GET", path, nil, &details); code != http.StatusOK {
+				t.Fatalf("get capsule: status = %d, want %d", code, http.StatusOK)
+			}
+			if answer := details.Predictions[0].Answer; answer == nil || answer.Answer != "Bob" {
+				t.Errorf("own answer = %+v, want Bob", answer)
+			}
+		})
+	}
+}
+
+func TestAnswerPredictionAfterOpening(t *testing.T) {
+	s := newTestServer(t)
+	c := s.seed(t, "Predictions", model.CapsuleStatusSealed)
+	predictionID := primitive.NewObjectID()
+	c.ContentItems = append(c.ContentItems, model.ContentItem{
+		Type:    model.ContentTypePrediction,
+		Content: &model.PredictionContent{ID: predictionID, Question: "Will it rain?", Answers: []model.PredictionAnswer{}},
+	})
+	if err := s.capsules.Update(context.Background(), c); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.capsules.UpdateStatus(context.Background(), c.ID, model.CapsuleStatusSealed, model.CapsuleStatusOpened); err != nil {
+		t.Fatal(err)
+	}
+
+	path := "/api/capsule/" + c.ID.Hex() + "/predictions/" + predictionID.Hex() + "/answer"
+	if code := s.do(t, "participant", "POST", path, map[string]string{"answer": "Yes"}, nil); code != http.StatusConflict {
+		t.Errorf("status = %d, want %d", code, http.StatusConflict)
+	}
+}
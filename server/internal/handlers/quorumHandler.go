@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/pateldivyesh1323/futflare/server/internal/store"
+	"github.com/pateldivyesh1323/futflare/server/internal/utils"
+)
+
+// TurnKey records the calling participant's approval to open a quorum capsule.
+func (h *Handler) TurnKey(w http.ResponseWriter, r *http.Request) {
+	h.setApproval(w, r, true)
+}
+
+// RevokeKey withdraws a previously turned key while the capsule is sealed.
+func (h *Handler) RevokeKey(w http.ResponseWriter, r *http.Request) {
+	h.setApproval(w, r, false)
+}
+
+func (h *Handler) setApproval(w http.ResponseWriter, r *http.Request, approved bool) {
+	params := mux.Vars(r)
+	id := params["id"]
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer")
+	userId, fullId, err := utils.GetIDFromToken(token)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusForbidden, "Unauthorized", nil)
+		return
+	}
+
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, "Invalid capsule ID", nil)
+		return
+	}
+
+	userDetails, err := h.GetUser(fullId)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusInternalServerError, "Internal server error", nil)
+		return
+	}
+
+	capsule, err := h.Capsules.GetForUser(r.Context(), objectId, userId, userDetails.Email)
+	if err != nil {
+		if err == store.ErrNotFound {
+			utils.SendJSONResponse(w, http.StatusNotFound, "Capsule not found", nil)
+		} else {
+			utils.SendJSONResponse(w, http.StatusInternalServerError, "Internal server error", nil)
+		}
+		return
+	}
+
+	if capsule.Quorum == nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, "Capsule does not open by quorum", nil)
+		return
+	}
+
+	isParticipant := false
+	for _, email := range capsule.ParticipantEmails {
+		if email == userDetails.Email {
+			isParticipant = true
+			break
+		}
+	}
+
+	if !isParticipant {
+		utils.SendJSONResponse(w, http.StatusForbidden, "Only participants hold a key to this capsule", nil)
+		return
+	}
+
+	err = h.Capsules.SetApproval(r.Context(), objectId, userDetails.Email, approved)
+	if err != nil {
+		if err == store.ErrNotFound {
+			utils.SendJSONResponse(w, http.StatusConflict, "Keys can only be turned while the capsule is sealed", nil)
+		} else {
+			utils.SendJSONResponse(w, http.StatusInternalServerError, "Internal server error", nil)
+		}
+		return
+	}
+
+	message := "Successfully turned key"
+	if !approved {
+		message = "Successfully revoked key"
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, message, nil)
+}
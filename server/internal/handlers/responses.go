@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/pateldivyesh1323/futflare/server/internal/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CapsuleSummary holds the capsule fields that are visible before it opens.
+// It is the shape used by every endpoint that lists capsules.
+type CapsuleSummary struct {
+	ID                primitive.ObjectID    `json:"id"`
+	Title             string                `json:"title"`
+	Description       string                `json:"description"`
+	Creator           string                `json:"creator"`
+	Status            model.CapsuleStatus   `json:"status"`
+	IsOpened          bool                  `json:"is_opened"`
+	ParticipantEmails []string              `json:"participant_emails"`
+	ScheduledOpenDate *time.Time            `json:"scheduled_open_date"`
+	CreatedAt         time.Time             `json:"created_at"`
+	Recurrence        *model.Recurrence     `json:"recurrence,omitempty"`
+	SeriesID          *primitive.ObjectID   `json:"series_id,omitempty"`
+	Quorum            *model.Quorum         `json:"quorum,omitempty"`
+	DeadMansSwitch    *model.DeadMansSwitch `json:"dead_mans_switch,omitempty"`
+	Lock              *model.PassphraseLock `json:"lock,omitempty"`
+	Geofence          *model.Geofence       `json:"geofence,omitempty"`
+}
+
+// CapsuleDetails is returned for a single capsule. ContentItems is always
+// present and stays empty while the content is hidden from the caller. The
+// creator of a draft gets their own items in ContentItems and those added by
+// participants in Contributions.
+type CapsuleDetails struct {
+	CapsuleSummary
+	ContentItems    []model.ContentItem    `json:"content_items"`
+	Contributions   []model.ContentItem    `json:"contributions,omitempty"`
+	NextRevealAt    *time.Time             `json:"next_reveal_at"`
+	IsLocked        bool                   `json:"is_locked"`
+	OutsideGeofence bool                   `json:"outside_geofence"`
+	LocationUnlocks []model.LocationUnlock `json:"location_unlocks,omitempty"`
+	// Predictions lists the questions to answer before the capsule opens.
+	Predictions      []PredictionQuestion    `json:"predictions,omitempty"`
+	PredictionScores []model.PredictionScore `json:"prediction_scores,omitempty"`
+	GoalsSummary     *model.GoalsSummary     `json:"goals_summary,omitempty"`
+}
+
+// PredictionQuestion is a prediction as seen before opening, with only the
+// caller's own answer.
+type PredictionQuestion struct {
+	ID       primitive.ObjectID      `json:"id"`
+	Question string                  `json:"question"`
+	Options  []string                `json:"options,omitempty"`
+	Answer   *model.PredictionAnswer `json:"answer"`
+}
+
+func newCapsuleSummary(c model.Capsule) CapsuleSummary {
+	summary := CapsuleSummary{
+		ID:                c.ID,
+		Title:             c.Title,
+		Description:       c.Description,
+		Creator:           c.Creator,
+		Status:            c.Status,
+		IsOpened:          c.IsOpened,
+		ParticipantEmails: c.ParticipantEmails,
+		CreatedAt:         c.CreatedAt,
+		Quorum:            c.Quorum,
+		DeadMansSwitch:    c.DeadMansSwitch,
+		Lock:              c.Lock,
+		Geofence:          c.Geofence,
+	}
+
+	if summary.ParticipantEmails == nil {
+		summary.ParticipantEmails = []string{}
+	}
+
+	// Capsules opened by a quorum or a dead man's switch may have no date.
+	if !c.ScheduledOpenDate.IsZero() {
+		date := c.ScheduledOpenDate
+		summary.ScheduledOpenDate = &date
+	}
+
+	if c.Recurrence != nil {
+		summary.Recurrence = c.Recurrence
+		summary.SeriesID = c.SeriesID
+	}
+
+	return summary
+}
+
+func newPredictionQuestions(items []model.ContentItem, email string) []PredictionQuestion {
+	var questions []PredictionQuestion
+	for _, item := range items {
+		prediction, ok := item.Content.(*model.PredictionContent)
+		if !ok {
+			continue
+		}
+
+		questions = append(questions, PredictionQuestion{
+			ID:       prediction.ID,
+			Question: prediction.Question,
+			Options:  prediction.Options,
+			Answer:   prediction.AnswerBy(email),
+		})
+	}
+	return questions
+}
+
+func newCapsuleSummaries(capsules []model.Capsule) []CapsuleSummary {
+	summaries := make([]CapsuleSummary, 0, len(capsules))
+	for _, c := range capsules {
+		summaries = append(summaries, newCapsuleSummary(c))
+	}
+	return summaries
+}
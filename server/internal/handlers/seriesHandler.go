@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/pateldivyesh1323/futflare/server/internal/store"
+	"github.com/pateldivyesh1323/futflare/server/internal/utils"
+)
+
+// GetCapsuleSeries lists every instance of the recurring series the capsule
+// belongs to, oldest open date first.
+func (h *Handler) GetCapsuleSeries(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id := params["id"]
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer")
+	userID, fullId, err := utils.GetIDFromToken(token)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusForbidden, "Unauthorized", nil)
+		return
+	}
+
+	userDetails, err := h.GetUser(fullId)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusInternalServerError, "Internal server error", nil)
+		return
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, "Invalid capsule ID", nil)
+		return
+	}
+
+	capsule, err := h.Capsules.GetForUser(r.Context(), objectID, userID, userDetails.Email)
+	if err != nil {
+		if err == store.ErrNotFound {
+			utils.SendJSONResponse(w, http.StatusNotFound, "Capsule not found", nil)
+		} else {
+			utils.SendJSONResponse(w, http.StatusInternalServerError, "Internal server error", nil)
+		}
+		return
+	}
+
+	if capsule.SeriesID == nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, "Capsule is not part of a recurring series", nil)
+		return
+	}
+
+	instances, err := h.Capsules.ListSeries(r.Context(), *capsule.SeriesID, userID, userDetails.Email)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusInternalServerError, "Internal server error", nil)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, "Successfully fetched capsule series", newCapsuleSummaries(instances))
+}
@@ -0,0 +1,629 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/pateldivyesh1323/futflare/server/internal/model"
+	"github.com/pateldivyesh1323/futflare/server/internal/storage"
+	"github.com/pateldivyesh1323/futflare/server/internal/store"
+	"github.com/pateldivyesh1323/futflare/server/internal/utils"
+)
+
+// The tus resumable upload protocol, see https://tus.io/protocols/resumable-upload.
+// The core protocol and the creation and termination extensions are
+// supported.
+const (
+	TUS_VERSION      = "1.0.0"
+	TUS_EXTENSIONS   = "creation,termination"
+	TUS_CONTENT_TYPE = "application/offset+octet-stream"
+	TUS_PATH         = "/api/tus/"
+)
+
+// TusOptions describes the tus support of the server.
+func (h *Handler) TusOptions(w http.ResponseWriter, r *http.Request) {
+	var maxSize int64
+	for contentType := range MAX_UPLOAD_SIZES {
+		maxSize = max(maxSize, maxUploadSize(contentType, true))
+	}
+
+	w.Header().Set("Tus-Resumable", TUS_VERSION)
+	w.Header().Set("Tus-Version", TUS_VERSION)
+	w.Header().Set("Tus-Extension", TUS_EXTENSIONS)
+	w.Header().Set("Tus-Max-Size", strconv.FormatInt(maxSize, 10))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CreateTusUpload creates an upload of Upload-Length bytes. Upload-Metadata
+// must name the draft capsule the file is added to once it is complete as
+// capsule_id, and the file as filename and filetype. content_type picks the
+// content item type, it defaults to the one matching filetype.
+func (h *Handler) CreateTusUpload(w http.ResponseWriter, r *http.Request) {
+	if !checkTusVersion(w, r) {
+		return
+	}
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer")
+	userId, fullId, err := utils.GetIDFromToken(token)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusUnauthorized, err.Error(), nil)
+		return
+	}
+
+	size, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || size < 0 {
+		utils.SendJSONResponse(w, http.StatusBadRequest, "Upload-Length is required", nil)
+		return
+	}
+
+	metadata, err := parseTusMetadata(r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	capsuleId, err := primitive.ObjectIDFromHex(metadata["capsule_id"])
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, "Upload-Metadata must contain a valid capsule_id", nil)
+		return
+	}
+
+	req := PresignedURLRequest{
+		ContentType: metadata["content_type"],
+		FileName:    metadata["filename"],
+		FileType:    metadata["filetype"],
+		FileSize:    size,
+	}
+	if req.ContentType == "" {
+		req.ContentType = string(contentTypeOf(req.FileType))
+	}
+
+	if err := validatePresignedURLRequest(req, true); err != nil {
+		status := http.StatusBadRequest
+		if size > maxUploadSize(model.ContentType(req.ContentType), true) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		utils.SendJSONResponse(w, status, err.Error(), nil)
+		return
+	}
+
+	userDetails, err := h.GetUser(fullId)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusInternalServerError, "Internal server error", nil)
+		return
+	}
+
+	if _, status, err := h.getUploadCapsule(r.Context(), capsuleId, userId, userDetails.Email, ""); err != nil {
+		utils.SendJSONResponse(w, status, err.Error(), nil)
+		return
+	}
+
+	objectKey := newObjectKey(userId, req.FileName)
+
+	uploadID, err := h.Files.CreateMultipart(r.Context(), objectKey, req.FileType)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusInternalServerError, "Failed to start upload", nil)
+		return
+	}
+
+	now := time.Now()
+	session := &model.UploadSession{
+		ID:          primitive.NewObjectID(),
+		UserID:      userId,
+		Tus:         true,
+		UploadID:    uploadID,
+		ObjectKey:   objectKey,
+		ContentType: model.ContentType(req.ContentType),
+		FileName:    req.FileName,
+		FileType:    req.FileType,
+		FileSize:    req.FileSize,
+		PartSize:    MULTIPART_PART_SIZE,
+		PartCount:   int32((req.FileSize + MULTIPART_PART_SIZE - 1) / MULTIPART_PART_SIZE),
+		Parts:       []model.UploadPart{},
+		CapsuleID:   &capsuleId,
+		Status:      model.UploadSessionUploading,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		ExpiresAt:   now.Add(UPLOAD_SESSION_EXPIRY),
+	}
+
+	if err := h.Uploads.CreateUploadSession(r.Context(), session); err != nil {
+		if err := h.Files.AbortMultipart(context.Background(), objectKey, uploadID); err != nil {
+			log.Printf("Error aborting upload %s: %v", objectKey, err)
+		}
+		utils.SendJSONResponse(w, http.StatusInternalServerError, "Internal server error", nil)
+		return
+	}
+
+	w.Header().Set("Tus-Resumable", TUS_VERSION)
+	w.Header().Set("Location", TUS_PATH+session.ID.Hex())
+	w.WriteHeader(http.StatusCreated)
+}
+
+// HeadTusUpload returns the offset an interrupted upload resumes from.
+func (h *Handler) HeadTusUpload(w http.ResponseWriter, r *http.Request) {
+	if !checkTusVersion(w, r) {
+		return
+	}
+
+	session, ok := h.getTusUpload(w, r)
+	if !ok {
+		return
+	}
+
+	offset := session.Offset()
+	if session.Status != model.UploadSessionUploading {
+		offset = session.FileSize
+	}
+
+	w.Header().Set("Tus-Resumable", TUS_VERSION)
+	w.Header().Set("Upload-Offset", strconv.FormatInt(offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(session.FileSize, 10))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+}
+
+// PatchTusUpload appends the request body to an upload at Upload-Offset.
+// Complete parts go straight to the object store, the rest is kept as the
+// pending tail of the upload until the next request fills the part. The
+// last request completes the file and adds it to the capsule. When that
+// fails, e.g. because the capsule is full, the client can retry it with an
+// empty request at the final offset.
+func (h *Handler) PatchTusUpload(w http.ResponseWriter, r *http.Request) {
+	if !checkTusVersion(w, r) {
+		return
+	}
+
+	if r.Header.Get("Content-Type") != TUS_CONTENT_TYPE {
+		utils.SendJSONResponse(w, http.StatusUnsupportedMediaType, "Content-Type must be "+TUS_CONTENT_TYPE, nil)
+		return
+	}
+
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, "Upload-Offset is required", nil)
+		return
+	}
+
+	session, ok := h.getTusUpload(w, r)
+	if !ok {
+		return
+	}
+
+	// A client retrying a request that is still being processed must not
+	// write the same part twice. Across servers the progress is only
+	// recorded when no other request recorded some first, see
+	// writeTusChunk.
+	if _, busy := h.tusUploads.LoadOrStore(session.ID, struct{}{}); busy {
+		utils.SendJSONResponse(w, http.StatusLocked, "Upload is being written by another request", nil)
+		return
+	}
+	defer h.tusUploads.Delete(session.ID)
+
+	// The request that held the lock may have written to the upload since
+	// it was loaded.
+	session, err = h.Uploads.GetUploadSession(r.Context(), session.ID, session.UserID)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusInternalServerError, "Internal server error", nil)
+		return
+	}
+
+	switch session.Status {
+	case model.UploadSessionUploading:
+	case model.UploadSessionAssembled:
+		if offset != session.FileSize {
+			utils.SendJSONResponse(w, http.StatusConflict, "Upload-Offset does not match the upload", nil)
+			return
+		}
+	default:
+		utils.SendJSONResponse(w, http.StatusConflict, "Upload is no longer in progress", nil)
+		return
+	}
+
+	if !time.Now().Before(session.ExpiresAt) {
+		utils.SendJSONResponse(w, http.StatusGone, "Upload expired, please start it again", nil)
+		return
+	}
+
+	// Keep what was received when the client disconnects midway.
+	ctx := context.WithoutCancel(r.Context())
+
+	if session.Status == model.UploadSessionUploading {
+		if offset != session.Offset() {
+			utils.SendJSONResponse(w, http.StatusConflict, "Upload-Offset does not match the upload", nil)
+			return
+		}
+
+		readErr, err := h.writeTusChunk(ctx, session, io.LimitReader(r.Body, session.FileSize-offset))
+		if err != nil {
+			if err == store.ErrNotFound {
+				utils.SendJSONResponse(w, http.StatusConflict, "Upload changed while it was being written, please resume it", nil)
+			} else {
+				utils.SendJSONResponse(w, http.StatusInternalServerError, "Failed to store upload", nil)
+			}
+			return
+		}
+
+		w.Header().Set("Tus-Resumable", TUS_VERSION)
+		w.Header().Set("Upload-Offset", strconv.FormatInt(session.Offset(), 10))
+
+		if readErr != nil {
+			utils.SendJSONResponse(w, http.StatusBadRequest, "Upload was interrupted, please resume it", nil)
+			return
+		}
+	} else {
+		w.Header().Set("Tus-Resumable", TUS_VERSION)
+		w.Header().Set("Upload-Offset", strconv.FormatInt(session.FileSize, 10))
+	}
+
+	if session.Offset() == session.FileSize || session.Status == model.UploadSessionAssembled {
+		if status, err := h.finishTusUpload(ctx, r, session); err != nil {
+			utils.SendJSONResponse(w, status, err.Error(), nil)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteTusUpload terminates an upload and discards its data.
+func (h *Handler) DeleteTusUpload(w http.ResponseWriter, r *http.Request) {
+	if !checkTusVersion(w, r) {
+		return
+	}
+
+	session, ok := h.getTusUpload(w, r)
+	if !ok {
+		return
+	}
+
+	if session.Status == model.UploadSessionCompleted {
+		utils.SendJSONResponse(w, http.StatusConflict, "Upload is no longer in progress", nil)
+		return
+	}
+
+	err := h.Uploads.UpdateUploadStatus(r.Context(), session.ID, session.Status, model.UploadSessionAborted)
+	if err != nil {
+		if err == store.ErrNotFound {
+			utils.SendJSONResponse(w, http.StatusConflict, "Upload is no longer in progress", nil)
+		} else {
+			utils.SendJSONResponse(w, http.StatusInternalServerError, "Internal server error", nil)
+		}
+		return
+	}
+
+	h.discardTusUpload(r.Context(), session)
+
+	w.Header().Set("Tus-Resumable", TUS_VERSION)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeTusChunk appends body to the upload and records the progress in the
+// session. readErr is set when the body ended before the client finished
+// sending it, the data received until then is kept.
+func (h *Handler) writeTusChunk(ctx context.Context, session *model.UploadSession, body io.Reader) (readErr error, err error) {
+	prevParts, prevTailSize := len(session.Parts), session.TailSize
+
+	previousTail := ""
+	if session.TailSize > 0 {
+		previousTail = storage.PendingKey(session.TailName())
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, session.PartSize))
+	if previousTail != "" {
+		tail, err := h.Files.ReadPrefix(ctx, previousTail, session.TailSize)
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(tail)) != session.TailSize {
+			return nil, fmt.Errorf("pending data of upload %s is incomplete", session.ID.Hex())
+		}
+		buf.Write(tail)
+	}
+
+	for {
+		_, copyErr := io.CopyN(buf, body, session.PartSize-int64(buf.Len()))
+
+		number := int32(len(session.Parts) + 1)
+		if number <= session.PartCount && int64(buf.Len()) == session.PartSizeOf(number) {
+			part, err := h.Files.PutPart(ctx, session.ObjectKey, session.UploadID, number, bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+			if err != nil {
+				return nil, err
+			}
+			session.Parts = append(session.Parts, model.UploadPart{Number: part.Number, ETag: part.ETag, Size: part.Size})
+			buf.Reset()
+		}
+
+		if copyErr == io.EOF {
+			break
+		}
+		if copyErr != nil {
+			readErr = copyErr
+			break
+		}
+	}
+
+	session.TailSize = int64(buf.Len())
+	if session.TailSize > 0 {
+		err := h.Files.Put(ctx, storage.PendingKey(session.TailName()), bytes.NewReader(buf.Bytes()), session.TailSize)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// A tail written by a request that loses the race is left to the media
+	// garbage collector, the winner may have written the same tail.
+	err = h.Uploads.SetUploadProgress(ctx, session.ID, prevParts, prevTailSize, session.Parts, session.TailSize)
+	if err != nil {
+		return nil, err
+	}
+
+	// The previous tail is obsolete once the new progress is recorded.
+	if previousTail != "" && previousTail != storage.PendingKey(session.TailName()) {
+		if err := h.Files.Delete(ctx, previousTail); err != nil {
+			log.Printf("Error deleting pending data %s: %v", previousTail, err)
+		}
+	}
+
+	return readErr, nil
+}
+
+// finishTusUpload joins the parts of a complete upload and adds the file to
+// its capsule. The session is only completed once the file is in the
+// capsule, until then the client can retry.
+func (h *Handler) finishTusUpload(ctx context.Context, r *http.Request, session *model.UploadSession) (int, error) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer")
+	_, fullId, _ := utils.GetIDFromToken(token)
+
+	userDetails, err := h.GetUser(fullId)
+	if err != nil {
+		return http.StatusInternalServerError, errors.New("Internal server error")
+	}
+
+	// The capsule is checked before the parts are joined, so a capsule that
+	// was sealed or filled meanwhile leaves the upload resumable.
+	capsule, status, err := h.getUploadCapsule(ctx, *session.CapsuleID, session.UserID, userDetails.Email, session.ObjectKey)
+	if err != nil {
+		return status, err
+	}
+
+	if session.Status == model.UploadSessionUploading {
+		parts := make([]storage.Part, 0, len(session.Parts))
+		for _, part := range session.Parts {
+			parts = append(parts, storage.Part{Number: part.Number, ETag: part.ETag, Size: part.Size})
+		}
+
+		if err := h.Files.CompleteMultipart(ctx, session.ObjectKey, session.UploadID, parts); err != nil {
+			return http.StatusInternalServerError, errors.New("Failed to complete upload")
+		}
+
+		err := h.Uploads.UpdateUploadStatus(ctx, session.ID, model.UploadSessionUploading, model.UploadSessionAssembled)
+		if err != nil {
+			if err == store.ErrNotFound {
+				return http.StatusConflict, errors.New("Upload is no longer in progress")
+			}
+			return http.StatusInternalServerError, errors.New("Internal server error")
+		}
+		session.Status = model.UploadSessionAssembled
+	}
+
+	if !capsuleHasUpload(capsule, session.ObjectKey) {
+		items := []model.ContentItem{uploadContentItem(session)}
+		if capsule.Creator != session.UserID {
+			items[0].ContributedBy = userDetails.Email
+		}
+
+		if status, err := h.verifyUploads(ctx, session.UserID, items, nil); err != nil {
+			return status, err
+		}
+
+		err = h.Capsules.AddContentItems(ctx, capsule.ID, items, MAX_CONTENT_ITEMS, MAX_CONTRIBUTIONS_PER_PARTICIPANT)
+		if err != nil {
+			if err == store.ErrNotFound {
+				return http.StatusConflict, errors.New("Capsule changed while adding your upload, please try again")
+			}
+			return http.StatusInternalServerError, errors.New("Internal server error")
+		}
+	}
+
+	err = h.Uploads.UpdateUploadStatus(ctx, session.ID, model.UploadSessionAssembled, model.UploadSessionCompleted)
+	if err != nil && err != store.ErrNotFound {
+		return http.StatusInternalServerError, errors.New("Internal server error")
+	}
+	session.Status = model.UploadSessionCompleted
+
+	return http.StatusOK, nil
+}
+
+// getUploadCapsule loads the draft capsule an upload is added to and checks
+// that the user may add one more item to it. A capsule that already holds
+// the object objectKey passes, the upload was added by an earlier attempt.
+func (h *Handler) getUploadCapsule(ctx context.Context, id primitive.ObjectID, userId, email, objectKey string) (*model.Capsule, int, error) {
+	capsule, err := h.Capsules.GetForContributor(ctx, id, userId, email)
+	if err != nil {
+		if err == store.ErrNotFound {
+			return nil, http.StatusNotFound, errors.New("Capsule not found")
+		}
+		return nil, http.StatusInternalServerError, errors.New("Internal server error")
+	}
+
+	if capsule.Status != model.CapsuleStatusDraft {
+		return nil, http.StatusConflict, errors.New("Uploads can only be added to a draft capsule")
+	}
+
+	if objectKey != "" && capsuleHasUpload(capsule, objectKey) {
+		return capsule, http.StatusOK, nil
+	}
+
+	if len(capsule.ContentItems) >= MAX_CONTENT_ITEMS {
+		return nil, http.StatusForbidden, fmt.Errorf("Capsule can hold maximum %d content items!", MAX_CONTENT_ITEMS)
+	}
+
+	if capsule.Creator != userId {
+		contributed := 0
+		for _, item := range capsule.ContentItems {
+			if item.ContributedBy == email {
+				contributed++
+			}
+		}
+
+		if contributed >= MAX_CONTRIBUTIONS_PER_PARTICIPANT {
+			return nil, http.StatusForbidden, fmt.Errorf("You can contribute maximum %d content items!", MAX_CONTRIBUTIONS_PER_PARTICIPANT)
+		}
+	}
+
+	return capsule, http.StatusOK, nil
+}
+
+// getTusUpload loads the tus upload addressed by the request. Users only see
+// their own uploads. It writes the error response itself.
+func (h *Handler) getTusUpload(w http.ResponseWriter, r *http.Request) (*model.UploadSession, bool) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer")
+	userId, _, err := utils.GetIDFromToken(token)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusUnauthorized, err.Error(), nil)
+		return nil, false
+	}
+
+	id, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusNotFound, "Upload not found", nil)
+		return nil, false
+	}
+
+	session, err := h.Uploads.GetUploadSession(r.Context(), id, userId)
+	if err == nil && !session.Tus {
+		err = store.ErrNotFound
+	}
+	if err != nil {
+		if err == store.ErrNotFound {
+			utils.SendJSONResponse(w, http.StatusNotFound, "Upload not found", nil)
+		} else {
+			utils.SendJSONResponse(w, http.StatusInternalServerError, "Internal server error", nil)
+		}
+		return nil, false
+	}
+
+	if session.Status == model.UploadSessionAborted {
+		utils.SendJSONResponse(w, http.StatusGone, "Upload was terminated", nil)
+		return nil, false
+	}
+
+	return session, true
+}
+
+// discardTusUpload deletes the parts and the pending tail of an upload, or
+// the joined file once it is assembled and no capsule refers to it.
+func (h *Handler) discardTusUpload(ctx context.Context, session *model.UploadSession) {
+	if session.Status == model.UploadSessionAssembled {
+		used, err := h.Capsules.UploadReferenced(ctx, model.Upload{ObjectKey: session.ObjectKey})
+		if err == nil && !used {
+			err = h.Files.Delete(ctx, session.ObjectKey)
+		}
+		if err != nil {
+			log.Printf("Error deleting upload %s: %v", session.ObjectKey, err)
+		}
+		return
+	}
+
+	err := h.Files.AbortMultipart(ctx, session.ObjectKey, session.UploadID)
+	if err != nil && err != storage.ErrNotFound {
+		log.Printf("Error aborting upload %s: %v", session.ObjectKey, err)
+	}
+
+	if session.TailSize > 0 {
+		if err := h.Files.Delete(ctx, storage.PendingKey(session.TailName())); err != nil {
+			log.Printf("Error deleting pending data of upload %s: %v", session.ID.Hex(), err)
+		}
+	}
+}
+
+// checkTusVersion rejects requests for a protocol version the server does
+// not speak.
+func checkTusVersion(w http.ResponseWriter, r *http.Request) bool {
+	if r.Header.Get("Tus-Resumable") != TUS_VERSION {
+		w.Header().Set("Tus-Version", TUS_VERSION)
+		utils.SendJSONResponse(w, http.StatusPreconditionFailed, "Unsupported tus version", nil)
+		return false
+	}
+	return true
+}
+
+// parseTusMetadata decodes an Upload-Metadata header, a comma separated list
+// of keys each followed by its base64 encoded value.
+func parseTusMetadata(header string) (map[string]string, error) {
+	metadata := map[string]string{}
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		key, encoded, _ := strings.Cut(pair, " ")
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid Upload-Metadata value for %s", key)
+		}
+		metadata[key] = string(value)
+	}
+	return metadata, nil
+}
+
+// contentTypeOf picks the content item type for a file type.
+func contentTypeOf(fileType string) model.ContentType {
+	switch strings.SplitN(fileType, "/", 2)[0] {
+	case "image":
+		return model.ContentTypeImage
+	case "video":
+		return model.ContentTypeVideo
+	case "audio":
+		return model.ContentTypeAudio
+	}
+	return model.ContentTypeFile
+}
+
+// capsuleHasUpload reports whether an item of the capsule holds the object.
+func capsuleHasUpload(capsule *model.Capsule, objectKey string) bool {
+	for _, item := range capsule.ContentItems {
+		if content, ok := item.Content.(model.UploadedContent); ok && content.GetUpload().ObjectKey == objectKey {
+			return true
+		}
+	}
+	return false
+}
+
+// uploadContentItem returns the content item for the file of an upload.
+func uploadContentItem(session *model.UploadSession) model.ContentItem {
+	upload := model.Upload{ObjectKey: session.ObjectKey}
+
+	var content model.ContentItemDetail
+	switch session.ContentType {
+	case model.ContentTypeImage:
+		content = &model.ImageContent{Upload: upload}
+	case model.ContentTypeVideo:
+		content = &model.VideoContent{Upload: upload}
+	case model.ContentTypeAudio:
+		content = &model.AudioContent{Upload: upload}
+	default:
+		content = &model.FileContent{
+			Upload:   upload,
+			Name:     session.FileName,
+			Size:     session.FileSize,
+			MimeType: session.FileType,
+		}
+	}
+
+	return model.ContentItem{Type: session.ContentType, Content: content}
+}
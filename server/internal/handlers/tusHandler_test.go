@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/pateldivyesh1323/futflare/server/internal/model"
+)
+
+// tus sends a tus request as user.
+func (s *testServer) tus(t *testing.T, user, method, path string, headers map[string]string, body []byte) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, bytes.NewReader(body))
+	req.Header.Set("Authorization", testToken(user))
+	req.Header.Set("Tus-Resumable", TUS_VERSION)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+// createTusUpload starts a tus upload of a video of size bytes into the
+// capsule and returns its path.
+func (s *testServer) createTusUpload(t *testing.T, user string, capsule *model.Capsule, size int) string {
+	t.Helper()
+
+	encode := func(value string) string { return base64.StdEncoding.EncodeToString([]byte(value)) }
+	w := s.tus(t, user, "POST", "/api/tus", map[string]string{
+		"Upload-Length":   strconv.Itoa(size),
+		"Upload-Metadata": "capsule_id " + encode(capsule.ID.Hex()) + ",filename " + encode("clip.mp4") + ",filetype " + encode("video/mp4"),
+	}, nil)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: status = %d, want %d", w.Code, http.StatusCreated)
+	}
+	return w.Header().Get("Location")
+}
+
+func (s *testServer) patchTus(t *testing.T, path string, offset int, chunk []byte) *httptest.ResponseRecorder {
+	t.Helper()
+
+	return s.tus(t, "creator", "PATCH", path, map[string]string{
+		"Content-Type":  TUS_CONTENT_TYPE,
+		"Upload-Offset": strconv.Itoa(offset),
+	}, chunk)
+}
+
+func testVideo(size int) []byte {
+	video := make([]byte, size)
+	copy(video, "\x00\x00\x00\x18ftypmp42")
+	return video
+}
+
+func TestTusUpload(t *testing.T) {
+	s := newTestServer(t)
+	c := s.seed(t, "Draft", model.CapsuleStatusDraft)
+
+	// Two parts, the chunks end in the middle of both.
+	video := testVideo(MULTIPART_PART_SIZE + 1000)
+	path := s.createTusUpload(t, "creator", c, len(video))
+
+	offset := func() int {
+		w := s.tus(t, "creator", "HEAD", path, nil, nil)
+		n, _ := strconv.Atoi(w.Header().Get("Upload-Offset"))
+		return n
+	}
+
+	chunks := []int{0, 4 << 20, MULTIPART_PART_SIZE + 500, len(video)}
+	for i := 0; i+1 < len(chunks); i++ {
+		from, to := chunks[i], chunks[i+1]
+		if w := s.patchTus(t, path, from, video[from:to]); w.Code != http.StatusNoContent {
+			t.Fatalf("chunk %d: status = %d, want %d", i+1, w.Code, http.StatusNoContent)
+		}
+		if got := offset(); got != to {
+			t.Fatalf("offset after chunk %d = %d, want %d", i+1, got, to)
+		}
+
+		if i == 0 {
+			if w := s.patchTus(t, path, 0, video[:10]); w.Code != http.StatusConflict {
+				t.Errorf("stale offset: status = %d, want %d", w.Code, http.StatusConflict)
+			}
+		}
+	}
+
+	if w := s.tus(t, "participant", "HEAD", path, nil, nil); w.Code != http.StatusNotFound {
+		t.Errorf("other user: status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	got := mustGetCapsule(t, s, c)
+	if len(got.ContentItems) != 2 || got.ContentItems[1].Type != model.ContentTypeVideo {
+		t.Fatalf("capsule items = %+v, want the message and the video", got.ContentItems)
+	}
+	key := uploadOf(got.ContentItems[1]).ObjectKey
+	info, err := s.files.Head(context.Background(), key)
+	if err != nil || info.Size != int64(len(video)) {
+		t.Errorf("object = %+v, %v, want %d bytes", info, err, len(video))
+	}
+
+	if w := s.patchTus(t, path, len(video), nil); w.Code != http.StatusConflict {
+		t.Errorf("patch after completing: status = %d, want %d", w.Code, http.StatusConflict)
+	}
+	if w := s.tus(t, "creator", "DELETE", path, nil, nil); w.Code != http.StatusConflict {
+		t.Errorf("terminate after completing: status = %d, want %d", w.Code, http.StatusConflict)
+	}
+}
+
+func TestTusUploadRetriesFinish(t *testing.T) {
+	s := newTestServer(t)
+	ctx := context.Background()
+	c := s.seed(t, "Draft", model.CapsuleStatusDraft)
+
+	video := testVideo(1000)
+	path := s.createTusUpload(t, "creator", c, len(video))
+
+	if err := s.capsules.UpdateStatus(ctx, c.ID, model.CapsuleStatusDraft, model.CapsuleStatusSealed); err != nil {
+		t.Fatal(err)
+	}
+	if w := s.patchTus(t, path, 0, video); w.Code != http.StatusConflict {
+		t.Fatalf("upload into a sealed capsule: status = %d, want %d", w.Code, http.StatusConflict)
+	}
+
+	// The data is kept, the client only repeats the final request.
+	if err := s.capsules.UpdateStatus(ctx, c.ID, model.CapsuleStatusSealed, model.CapsuleStatusDraft); err != nil {
+		t.Fatal(err)
+	}
+	if w := s.patchTus(t, path, len(video), nil); w.Code != http.StatusNoContent {
+		t.Fatalf("retry: status = %d, want %d", w.Code, http.StatusNoContent)
+	}
+	if w := s.patchTus(t, path, len(video), nil); w.Code != http.StatusConflict {
+		t.Errorf("second retry: status = %d, want %d", w.Code, http.StatusConflict)
+	}
+
+	if got := mustGetCapsule(t, s, c); len(got.ContentItems) != 2 {
+		t.Errorf("capsule has %d items, want the upload added once", len(got.ContentItems))
+	}
+}
+
+func TestTusUploadTerminate(t *testing.T) {
+	s := newTestServer(t)
+	c := s.seed(t, "Draft", model.CapsuleStatusDraft)
+
+	video := testVideo(1000)
+	path := s.createTusUpload(t, "creator", c, len(video))
+	if w := s.patchTus(t, path, 0, video[:500]); w.Code != http.StatusNoContent {
+		t.Fatalf("chunk: status = %d, want %d", w.Code, http.StatusNoContent)
+	}
+
+	if w := s.tus(t, "creator", "DELETE", path, nil, nil); w.Code != http.StatusNoContent {
+		t.Fatalf("terminate: status = %d, want %d", w.Code, http.StatusNoContent)
+	}
+	if w := s.tus(t, "creator", "HEAD", path, nil, nil); w.Code != http.StatusGone {
+		t.Errorf("HEAD after terminating: status = %d, want %d", w.Code, http.StatusGone)
+	}
+
+	objects, err := s.files.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 0 {
+		t.Errorf("objects %+v are left after terminating, want none", objects)
+	}
+}
+
+func TestCreateTusUpload(t *testing.T) {
+	s := newTestServer(t)
+	draft := s.seed(t, "Draft", model.CapsuleStatusDraft)
+	sealed := s.seed(t, "Sealed", model.CapsuleStatusSealed)
+	encode := func(value string) string { return base64.StdEncoding.EncodeToString([]byte(value)) }
+	metadata := func(c *model.Capsule) string {
+		return "capsule_id " + encode(c.ID.Hex()) + ",filename " + encode("clip.mp4") + ",filetype " + encode("video/mp4")
+	}
+
+	tests := []struct {
+		name    string
+		user    string
+		headers map[string]string
+		want    int
+	}{
+		{"no length", "creator", map[string]string{"Upload-Metadata": metadata(draft)}, http.StatusBadRequest},
+		{"too large", "creator", map[string]string{"Upload-Length": strconv.FormatInt(maxUploadSize(model.ContentTypeVideo, true)+1, 10), "Upload-Metadata": metadata(draft)}, http.StatusRequestEntityTooLarge},
+		{"invalid metadata", "creator", map[string]string{"Upload-Length": "10", "Upload-Metadata": "capsule_id !!!"}, http.StatusBadRequest},
+		{"sealed capsule", "creator", map[string]string{"Upload-Length": "10", "Upload-Metadata": metadata(sealed)}, http.StatusConflict},
+		{"stranger", "stranger", map[string]string{"Upload-Length": "10", "Upload-Metadata": metadata(draft)}, http.StatusNotFound},
+		{"participant", "participant", map[string]string{"Upload-Length": "10", "Upload-Metadata": metadata(draft)}, http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := s.tus(t, tt.user, "POST", "/api/tus", tt.headers, nil); w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func mustGetCapsule(t *testing.T, s *testServer, c *model.Capsule) *model.Capsule {
+	t.Helper()
+
+	got, err := s.capsules.GetForUser(context.Background(), c.ID, "creator", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
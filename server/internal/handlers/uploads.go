@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/pateldivyesh1323/futflare/server/internal/model"
+	"github.com/pateldivyesh1323/futflare/server/internal/storage"
+)
+
+const (
+	// SNIFF_LENGTH is the number of bytes http.DetectContentType looks at.
+	SNIFF_LENGTH        = 512
+	DOWNLOAD_URL_EXPIRY = 15 * time.Minute
+)
+
+// safeFileName replaces the characters of a file name that are not safe in
+// an object key or URL.
+func safeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		}
+		return '-'
+	}, name)
+}
+
+// uploadPrefix returns the prefix of the keys of the objects a user uploads.
+// Users can only add their own uploads to a capsule, so they cannot get a
+// download link for somebody else's object by guessing its key.
+func uploadPrefix(userId string) string {
+	return "uploads/" + safeFileName(userId) + "/"
+}
+
+// newObjectKey returns a fresh key for a file the user uploads.
+func newObjectKey(userId, fileName string) string {
+	return uploadPrefix(userId) + primitive.NewObjectID().Hex() + "-" + safeFileName(fileName)
+}
+
+// uploadOf returns the upload an item refers to, or nil for items without
+// one.
+func uploadOf(item model.ContentItem) *model.Upload {
+	if content, ok := item.Content.(model.UploadedContent); ok {
+		return content.GetUpload()
+	}
+	return nil
+}
+
+// uploadKeys returns the object keys of the uploads among items.
+func uploadKeys(items []model.ContentItem) []string {
+	var keys []string
+	for _, item := range items {
+		upload := uploadOf(item)
+		if upload == nil {
+			continue
+		}
+
+		key := upload.ObjectKey
+		if key == "" {
+			var ok bool
+			if key, ok = storage.KeyFromLegacyURL(upload.URL); !ok {
+				continue
+			}
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// verifyUploads checks that every object referenced by items was uploaded by
+// userId, exists in the object store and that its size and content match
+// the item. Items sent with a bucket URL instead of an object key are
+// converted, only the key is stored. Uploads already referenced by existing
+// were verified when they were added.
+func (h *Handler) verifyUploads(ctx context.Context, userId string, items []model.ContentItem, existing []model.ContentItem) (int, error) {
+	verified := map[string]bool{}
+	for _, item := range existing {
+		if upload := uploadOf(item); upload != nil {
+			verified[upload.ObjectKey] = true
+			verified[upload.URL] = true
+		}
+	}
+
+	for _, item := range items {
+		upload := uploadOf(item)
+		if upload == nil {
+			continue
+		}
+
+		// Items stored before uploads were private keep their URL.
+		if upload.ObjectKey == "" && verified[upload.URL] {
+			continue
+		}
+
+		if upload.ObjectKey == "" {
+			key, ok := storage.KeyFromLegacyURL(upload.URL)
+			if !ok {
+				return http.StatusBadRequest, fmt.Errorf("%s must be uploaded through Futflare: %s", item.Type, upload.URL)
+			}
+			upload.ObjectKey = key
+		}
+		upload.URL = ""
+
+		if verified[upload.ObjectKey] {
+			continue
+		}
+
+		if !strings.HasPrefix(upload.ObjectKey, uploadPrefix(userId)) {
+			return http.StatusForbidden, fmt.Errorf("%s %s was not uploaded by you", item.Type, upload.ObjectKey)
+		}
+
+		if status, err := verifyUpload(ctx, h.Files, item, upload.ObjectKey); err != nil {
+			return status, err
+		}
+		verified[upload.ObjectKey] = true
+	}
+
+	return http.StatusOK, nil
+}
+
+// signUploads sets the URL of every upload among items to a short lived
+// download link. Only call it for content the user is allowed to see.
+func (h *Handler) signUploads(ctx context.Context, items []model.ContentItem) error {
+	for _, item := range items {
+		upload := uploadOf(item)
+		if upload == nil {
+			continue
+		}
+
+		key := upload.ObjectKey
+		if key == "" {
+			var ok bool
+			if key, ok = storage.KeyFromLegacyURL(upload.URL); !ok {
+				// An external URL of an item created before uploads were
+				// verified.
+				continue
+			}
+		}
+
+		url, err := h.Files.PresignGet(ctx, key, DOWNLOAD_URL_EXPIRY)
+		if err != nil {
+			return err
+		}
+		upload.URL = url
+	}
+
+	return nil
+}
+
+func verifyUpload(ctx context.Context, files storage.ObjectStore, item model.ContentItem, key string) (int, error) {
+	file, isFile := item.Content.(*model.FileContent)
+
+	info, err := files.Head(ctx, key)
+	if err == storage.ErrNotFound {
+		return http.StatusBadRequest, fmt.Errorf("Upload of %s %s did not finish, please upload it again", item.Type, key)
+	}
+	if err != nil {
+		return http.StatusInternalServerError, errors.New("Failed to verify uploaded files")
+	}
+
+	// Single uploads are held to their smaller limit by the signed size.
+	if info.Size == 0 || info.Size > maxUploadSize(item.Type, true) {
+		return http.StatusBadRequest, fmt.Errorf("Uploaded %s %s has an invalid size", item.Type, key)
+	}
+
+	if isFile && info.Size != file.Size {
+		return http.StatusBadRequest, fmt.Errorf("File %s does not match the uploaded size", file.Name)
+	}
+
+	head, err := files.ReadPrefix(ctx, key, SNIFF_LENGTH)
+	if err != nil {
+		return http.StatusInternalServerError, errors.New("Failed to verify uploaded files")
+	}
+
+	if !matchesContentType(item, sniffContentType(head)) {
+		return http.StatusBadRequest, fmt.Errorf("Uploaded %s %s is not a valid %s", item.Type, key, item.Type)
+	}
+
+	return http.StatusOK, nil
+}
+
+// sniffContentType detects the media type of a file from its first bytes.
+// ISO media files such as QuickTime videos and M4A audio, which
+// http.DetectContentType only partly knows, are reported as video/mp4.
+func sniffContentType(head []byte) string {
+	if len(head) >= 8 && string(head[4:8]) == "ftyp" {
+		return "video/mp4"
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	return mediaType
+}
+
+// matchesContentType reports whether a sniffed media type is acceptable for
+// the type of an item.
+func matchesContentType(item model.ContentItem, sniffed string) bool {
+	switch item.Type {
+	case model.ContentTypeImage:
+		return strings.HasPrefix(sniffed, "image/")
+	case model.ContentTypeVideo:
+		return strings.HasPrefix(sniffed, "video/")
+	case model.ContentTypeAudio:
+		// Audio is often stored in the containers of video formats.
+		return strings.HasPrefix(sniffed, "audio/") ||
+			sniffed == "video/mp4" || sniffed == "video/webm" || sniffed == "application/ogg"
+	case model.ContentTypeFile:
+		return matchesFileType(item.Content.(*model.FileContent).MimeType, sniffed)
+	}
+	return false
+}
+
+// matchesFileType compares the declared type of a file with its content.
+// Most documents cannot be recognized from their first bytes, only the
+// formats that can are held to their declared type.
+func matchesFileType(declared, sniffed string) bool {
+	declared, _, _ = mime.ParseMediaType(declared)
+
+	switch {
+	case sniffed == "application/octet-stream", sniffed == "text/plain":
+		return true
+	case sniffed == declared:
+		return true
+	case sniffed == "application/zip":
+		// Office documents, OpenDocument files and EPUBs are zip archives.
+		return strings.Contains(declared, "zip") || strings.Contains(declared, "openxmlformats") ||
+			strings.Contains(declared, "opendocument") || declared == "application/epub+zip"
+	case sniffed == "text/html", sniffed == "text/xml":
+		return false
+	}
+
+	return strings.SplitN(sniffed, "/", 2)[0] == strings.SplitN(declared, "/", 2)[0]
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/pateldivyesh1323/futflare/server/internal/config"
+	"github.com/pateldivyesh1323/futflare/server/internal/model"
+	"github.com/pateldivyesh1323/futflare/server/internal/storage"
+	"github.com/pateldivyesh1323/futflare/server/internal/store"
+)
+
+var (
+	testPNG  = append([]byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), make([]byte, 32)...)
+	testMP4  = append([]byte("\x00\x00\x00\x18ftypmp42\x00\x00\x00\x00mp42isom"), make([]byte, 32)...)
+	testHTML = []byte("<!DOCTYPE html><html><script>alert(1)</script></html>")
+	testPDF  = []byte("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n1 0 obj\n<<>>\nendobj\n")
+)
+
+func TestSniffContentType(t *testing.T) {
+	tests := []struct {
+		head []byte
+		want string
+	}{
+		{testPNG, "image/png"},
+		{testMP4, "video/mp4"},
+		{append([]byte("\x00\x00\x00\x1cftypM4A "), make([]byte, 16)...), "video/mp4"},
+		{testHTML, "text/html"},
+		{testPDF, "application/pdf"},
+		{[]byte{0x00, 0x01, 0x02, 0x03}, "application/octet-stream"},
+	}
+
+	for _, tt := range tests {
+		if got := sniffContentType(tt.head); got != tt.want {
+			t.Errorf("sniffContentType(%q) = %s, want %s", tt.head[:4], got, tt.want)
+		}
+	}
+}
+
+func TestMatchesContentType(t *testing.T) {
+	file := func(mimeType string) model.ContentItem {
+		return model.ContentItem{Type: model.ContentTypeFile, Content: &model.FileContent{MimeType: mimeType}}
+	}
+
+	tests := []struct {
+		name    string
+		item    model.ContentItem
+		sniffed string
+		want    bool
+	}{
+		{"image", model.ContentItem{Type: model.ContentTypeImage}, "image/png", true},
+		{"html as image", model.ContentItem{Type: model.ContentTypeImage}, "text/html", false},
+		{"video", model.ContentItem{Type: model.ContentTypeVideo}, "video/mp4", true},
+		{"audio in mp4", model.ContentItem{Type: model.ContentTypeAudio}, "video/mp4", true},
+		{"image as audio", model.ContentItem{Type: model.ContentTypeAudio}, "image/png", false},
+		{"pdf", file("application/pdf"), "application/pdf", true},
+		{"unknown document", file("application/msword"), "application/octet-stream", true},
+		{"docx", file("application/vnd.openxmlformats-officedocument.wordprocessingml.document"), "application/zip", true},
+		{"zip as pdf", file("application/pdf"), "application/zip", false},
+		{"html as text", file("text/plain"), "text/html", false},
+		{"html as html", file("text/html"), "text/html", true},
+		{"jpeg declared as png", file("image/png"), "image/jpeg", true},
+		{"image declared as pdf", file("application/pdf"), "image/png", false},
+	}
+
+	for _, tt := range tests {
+		if got := matchesContentType(tt.item, tt.sniffed); got != tt.want {
+			t.Errorf("%s: matchesContentType = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestVerifyUploads(t *testing.T) {
+	ctx := context.Background()
+	files, err := storage.NewLocalStore(t.TempDir(), "http://localhost", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandler(store.NewMemoryCapsuleStore(), store.NewMemoryUploadSessionStore(), files)
+
+	bucket := config.AWSS3Bucket
+	config.AWSS3Bucket = "futflare-test"
+	t.Cleanup(func() { config.AWSS3Bucket = bucket })
+
+	own := uploadPrefix("creator")
+	objects := map[string][]byte{
+		own + "photo.png":                   testPNG,
+		own + "page.png":                    testHTML,
+		own + "report.pdf":                  testPDF,
+		own + "legacy.png":                  testPNG,
+		uploadPrefix("other") + "photo.png": testPNG,
+	}
+	for key, body := range objects {
+		if err := files.Put(ctx, key, bytes.NewReader(body), int64(len(body))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	image := func(upload model.Upload) model.ContentItem {
+		return model.ContentItem{Type: model.ContentTypeImage, Content: &model.ImageContent{Upload: upload}}
+	}
+	pdf := func(size int64) model.ContentItem {
+		return model.ContentItem{Type: model.ContentTypeFile, Content: &model.FileContent{
+			Upload:   model.Upload{ObjectKey: own + "report.pdf"},
+			Name:     "report.pdf",
+			Size:     size,
+			MimeType: "application/pdf",
+		}}
+	}
+
+	tests := []struct {
+		name     string
+		item     model.ContentItem
+		existing []model.ContentItem
+		want     int
+	}{
+		{"own image", image(model.Upload{ObjectKey: own + "photo.png"}), nil, http.StatusOK},
+		{"file", pdf(int64(len(testPDF))), nil, http.StatusOK},
+		{"legacy URL", image(model.Upload{URL: storage.LegacyURL(own + "legacy.png")}), nil, http.StatusOK},
+		{"html disguised as image", image(model.Upload{ObjectKey: own + "page.png"}), nil, http.StatusBadRequest},
+		{"file of another size", pdf(1), nil, http.StatusBadRequest},
+		{"missing object", image(model.Upload{ObjectKey: own + "missing.png"}), nil, http.StatusBadRequest},
+		{"upload of another user", image(model.Upload{ObjectKey: uploadPrefix("other") + "photo.png"}), nil, http.StatusForbidden},
+		{"external URL", image(model.Upload{URL: "https://example.com/photo.png"}), nil, http.StatusBadRequest},
+		{
+			name:     "upload of another user already in the capsule",
+			item:     image(model.Upload{ObjectKey: uploadPrefix("other") + "photo.png"}),
+			existing: []model.ContentItem{image(model.Upload{ObjectKey: uploadPrefix("other") + "photo.png"})},
+			want:     http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, err := h.verifyUploads(ctx, "creator", []model.ContentItem{tt.item}, tt.existing)
+			if status != tt.want {
+				t.Errorf("verifyUploads = %d, %v, want %d", status, err, tt.want)
+			}
+		})
+	}
+
+	legacy := image(model.Upload{URL: storage.LegacyURL(own + "legacy.png")})
+	if _, err := h.verifyUploads(ctx, "creator", []model.ContentItem{legacy}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if upload := uploadOf(legacy); upload.ObjectKey != own+"legacy.png" || upload.URL != "" {
+		t.Errorf("legacy upload = %+v, want it converted to its object key", upload)
+	}
+}
@@ -0,0 +1,54 @@
+// Package markdown renders user written Markdown to HTML that is safe to
+// show in the browser.
+package markdown
+
+import (
+	"bytes"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+var (
+	// Raw HTML is passed through by the renderer so a limited set of tags
+	// can be written inline, the policy removes everything else.
+	converter = goldmark.New(
+		goldmark.WithExtensions(extension.Strikethrough, extension.Linkify),
+		goldmark.WithRendererOptions(html.WithHardWraps(), html.WithUnsafe()),
+	)
+	policy = newPolicy()
+)
+
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+
+	p.AllowElements(
+		"p", "br", "hr", "blockquote", "pre", "code",
+		"h1", "h2", "h3", "h4", "h5", "h6",
+		"strong", "b", "em", "i", "u", "del", "s", "sub", "sup",
+		"ul", "ol", "li",
+	)
+	p.AllowAttrs("start").Matching(bluemonday.Integer).OnElements("ol")
+
+	p.AllowAttrs("href").OnElements("a")
+	p.AllowURLSchemes("http", "https", "mailto")
+	p.RequireParseableURLs(true)
+	p.RequireNoFollowOnLinks(true)
+	p.RequireNoReferrerOnLinks(true)
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+
+	return p
+}
+
+// ToHTML renders source and strips every element and attribute that is not
+// on the allowlist.
+func ToHTML(source string) (string, error) {
+	var buf bytes.Buffer
+	if err := converter.Convert([]byte(source), &buf); err != nil {
+		return "", err
+	}
+
+	return policy.Sanitize(buf.String()), nil
+}
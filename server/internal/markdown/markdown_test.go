@@ -0,0 +1,73 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToHTML(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "formatting",
+			source:   "# Title\n\n**bold** and ~~gone~~\n\n1. one\n2. two",
+			contains: []string{"<h1>Title</h1>", "<strong>bold</strong>", "<del>gone</del>", "<ol>", "<li>one</li>"},
+		},
+		{
+			name:     "links",
+			source:   "[site](https://example.com) https://example.org",
+			contains: []string{`href="https://example.com"`, `href="https://example.org"`, `rel="nofollow noreferrer noopener"`, `target="_blank"`},
+		},
+		{
+			name:     "script",
+			source:   "Hello <script>alert(1)</script>",
+			contains: []string{"Hello"},
+			excludes: []string{"<script", "alert(1)"},
+		},
+		{
+			name:     "event handlers",
+			source:   `<b onclick="alert(1)">bold</b> <img src="x" onerror="alert(1)">`,
+			contains: []string{"<b>bold</b>"},
+			excludes: []string{"onclick", "onerror", "<img"},
+		},
+		{
+			name:     "javascript links",
+			source:   "[click](javascript:alert(1)) <a href=\"javascript:alert(1)\">raw</a>",
+			excludes: []string{"javascript:"},
+		},
+		{
+			name:     "data links",
+			source:   "[click](data:text/html;base64,PHNjcmlwdD4=)",
+			excludes: []string{"data:"},
+		},
+		{
+			name:     "frames and styles",
+			source:   `<iframe src="https://example.com"></iframe><style>body{}</style><p style="color:red">text</p>`,
+			contains: []string{"<p>text</p>"},
+			excludes: []string{"<iframe", "<style", "style="},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToHTML(tt.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range tt.contains {
+				if !strings.Contains(got, s) {
+					t.Errorf("ToHTML(%q) = %q, want it to contain %q", tt.source, got, s)
+				}
+			}
+			for _, s := range tt.excludes {
+				if strings.Contains(got, s) {
+					t.Errorf("ToHTML(%q) = %q, want it without %q", tt.source, got, s)
+				}
+			}
+		})
+	}
+}
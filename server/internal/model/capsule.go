@@ -1,8 +1,6 @@
 package model
 
 import (
-	"encoding/json"
-	"fmt"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -11,83 +9,115 @@ import (
 type ContentType string
 
 const (
-	ContentTypeImage   ContentType = "image"
-	ContentTypeVideo   ContentType = "video"
-	ContentTypeMessage ContentType = "message"
+	ContentTypeImage      ContentType = "image"
+	ContentTypeVideo      ContentType = "video"
+	ContentTypeMessage    ContentType = "message"
+	ContentTypeAudio      ContentType = "audio"
+	ContentTypeFile       ContentType = "file"
+	ContentTypePrediction ContentType = "prediction"
+	ContentTypeGoals      ContentType = "goals"
+	ContentTypeLink       ContentType = "link"
 )
 
+type CapsuleStatus string
+
+const (
+	CapsuleStatusDraft    CapsuleStatus = "draft"
+	CapsuleStatusSealed   CapsuleStatus = "sealed"
+	CapsuleStatusOpened   CapsuleStatus = "opened"
+	CapsuleStatusArchived CapsuleStatus = "archived"
+)
+
+var capsuleTransitions = map[CapsuleStatus][]CapsuleStatus{
+	CapsuleStatusDraft:  {CapsuleStatusSealed, CapsuleStatusArchived},
+	CapsuleStatusSealed: {CapsuleStatusOpened, CapsuleStatusArchived},
+	CapsuleStatusOpened: {CapsuleStatusArchived},
+}
+
+// CanTransitionTo reports whether a capsule in status s may move to next.
+func (s CapsuleStatus) CanTransitionTo(next CapsuleStatus) bool {
+	for _, allowed := range capsuleTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
 type Capsule struct {
 	ID                primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
 	Title             string             `bson:"title,omitempty" json:"title"`
 	Description       string             `bson:"description,omitempty" json:"description"`
 	Creator           string             `bson:"creator,omitempty" json:"creator,omitempty"`
-	IsOpened          bool               `bson:"is_opened" json:"is_opened,omitempty"`
+	Status            CapsuleStatus      `bson:"status" json:"status,omitempty"`
+	IsOpened          bool               `bson:"is_opened" json:"is_opened"`
 	ParticipantEmails []string           `bson:"participant_emails" json:"participant_emails"`
 	ScheduledOpenDate time.Time          `bson:"scheduled_open_date" json:"scheduled_open_date"`
 	ContentItems      []ContentItem      `bson:"content_items" json:"content_items"`
-	CreatedAt         time.Time          `bson:"created_at,omitempty" json:"created_at,omitempty"`
+	NextRevealAt      *time.Time         `bson:"next_reveal_at,omitempty" json:"next_reveal_at,omitempty"`
+	Recurrence        *Recurrence        `bson:"recurrence,omitempty" json:"recurrence,omitempty"`
+	Quorum            *Quorum            `bson:"quorum,omitempty" json:"quorum,omitempty"`
+	DeadMansSwitch    *DeadMansSwitch    `bson:"dead_mans_switch,omitempty" json:"dead_mans_switch,omitempty"`
+	Lock              *PassphraseLock    `bson:"lock,omitempty" json:"lock,omitempty"`
+	Geofence          *Geofence          `bson:"geofence,omitempty" json:"geofence,omitempty"`
+	LocationUnlocks   []LocationUnlock   `bson:"location_unlocks,omitempty" json:"-"`
+	// SeriesID is the ID of the first capsule of a recurring series, shared by
+	// every instance spawned from it.
+	SeriesID *primitive.ObjectID `bson:"series_id,omitempty" json:"series_id,omitempty"`
+	// NextInstanceID links an opened recurring capsule to the instance spawned
+	// after it. It is stored as null once the series has ended.
+	NextInstanceID *primitive.ObjectID `bson:"next_instance_id,omitempty" json:"next_instance_id,omitempty"`
+	CreatedAt      time.Time           `bson:"created_at,omitempty" json:"created_at,omitempty"`
 }
 
-type ContentItem struct {
-	Type    ContentType       `bson:"type" json:"type"`
-	Content ContentItemDetail `bson:"content" json:"content"`
-}
+// ReadyToOpen reports whether a sealed capsule should be opened at now.
+func (c *Capsule) ReadyToOpen(now time.Time) bool {
+	if c.Status != CapsuleStatusSealed {
+		return false
+	}
 
-type ContentItemDetail interface{}
+	if c.DeadMansSwitch != nil {
+		return !c.DeadMansSwitch.OpensAt.After(now)
+	}
 
-type MessageContent struct {
-	Text string `bson:"text" json:"text"`
-}
+	datePassed := !c.ScheduledOpenDate.IsZero() && !c.ScheduledOpenDate.After(now)
+	if c.Quorum == nil {
+		return datePassed
+	}
 
-type ImageContent struct {
-	URL     string `bson:"url" json:"url"`
-	Caption string `bson:"caption,omitempty" json:"caption,omitempty"`
-	AltText string `bson:"alt_text,omitempty" json:"alt_text,omitempty"`
+	if c.Quorum.RequireDate && !datePassed {
+		return false
+	}
+	return c.Quorum.Reached()
 }
 
-type VideoContent struct {
-	URL     string `bson:"url" json:"url"`
-	Caption string `bson:"caption,omitempty" json:"caption,omitempty"`
+type ContentItem struct {
+	Type    ContentType       `bson:"type" json:"type"`
+	Content ContentItemDetail `bson:"content" json:"content"`
+	// ContributedBy is the email of the participant who added the item. It is
+	// empty for items added by the creator.
+	ContributedBy string `bson:"contributed_by,omitempty" json:"contributed_by,omitempty"`
+	// RevealAt delays the item past the capsule's open date. Items without it
+	// are revealed as soon as the capsule opens.
+	RevealAt *time.Time `bson:"reveal_at,omitempty" json:"reveal_at,omitempty"`
 }
 
-func (ci *ContentItem) UnmarshalJSON(data []byte) error {
-	temp := struct {
-		Type    ContentType     `json:"type"`
-		Content json.RawMessage `json:"content"`
-	}{}
-
-	if err := json.Unmarshal(data, &temp); err != nil {
-		return err
-	}
-
-	ci.Type = temp.Type
+func (ci ContentItem) IsRevealed(now time.Time) bool {
+	return ci.RevealAt == nil || !ci.RevealAt.After(now)
+}
 
-	var err error
-	switch ci.Type {
-	case ContentTypeMessage:
-		var msgContent MessageContent
-		if err = json.Unmarshal(temp.Content, &msgContent); err != nil {
-			return fmt.Errorf("invalid message content: %w", err)
+// NextReveal returns the earliest item reveal time after the given time, or
+// nil when no item is waiting to be revealed.
+func (c *Capsule) NextReveal(after time.Time) *time.Time {
+	var next *time.Time
+	for _, item := range c.ContentItems {
+		if item.RevealAt == nil || !item.RevealAt.After(after) {
+			continue
 		}
-		ci.Content = msgContent
-
-	case ContentTypeImage:
-		var imgContent ImageContent
-		if err = json.Unmarshal(temp.Content, &imgContent); err != nil {
-			return fmt.Errorf("invalid image content: %w", err)
+		if next == nil || item.RevealAt.Before(*next) {
+			revealAt := *item.RevealAt
+			next = &revealAt
 		}
-		ci.Content = imgContent
-
-	case ContentTypeVideo:
-		var vidContent VideoContent
-		if err = json.Unmarshal(temp.Content, &vidContent); err != nil {
-			return fmt.Errorf("invalid video content: %w", err)
-		}
-		ci.Content = vidContent
-
-	default:
-		return fmt.Errorf("unknown content type: %s", ci.Type)
 	}
-
-	return nil
+	return next
 }
@@ -0,0 +1,277 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ContentDetail is implemented by the content of every content type.
+type ContentDetail interface {
+	// Validate checks the fields supplied by the user. Its errors are shown
+	// to the user as is.
+	Validate() error
+	// Render returns the representation of the content sent to clients.
+	Render() interface{}
+}
+
+// ContentItemDetail holds a ContentDetail. Only stored items of a content
+// type that is no longer registered keep their raw bson.M content.
+type ContentItemDetail interface{}
+
+var contentTypes = map[ContentType]func() ContentDetail{}
+
+// RegisterContentType makes a content type known to the decoder. newDetail
+// returns a pointer to an empty detail that the content is decoded into.
+func RegisterContentType(contentType ContentType, newDetail func() ContentDetail) {
+	contentTypes[contentType] = newDetail
+}
+
+func init() {
+	RegisterContentType(ContentTypeMessage, func() ContentDetail { return &MessageContent{} })
+	RegisterContentType(ContentTypeImage, func() ContentDetail { return &ImageContent{} })
+	RegisterContentType(ContentTypeVideo, func() ContentDetail { return &VideoContent{} })
+	RegisterContentType(ContentTypeAudio, func() ContentDetail { return &AudioContent{} })
+	RegisterContentType(ContentTypeFile, func() ContentDetail { return &FileContent{} })
+	RegisterContentType(ContentTypePrediction, func() ContentDetail { return &PredictionContent{} })
+	RegisterContentType(ContentTypeGoals, func() ContentDetail { return &GoalsContent{} })
+	RegisterContentType(ContentTypeLink, func() ContentDetail { return &LinkContent{} })
+}
+
+const MaxMessageLength = 20000
+
+type MessageFormat string
+
+const (
+	MessageFormatPlain    MessageFormat = "plain"
+	MessageFormatMarkdown MessageFormat = "markdown"
+)
+
+// MessageContent is a written message. Markdown messages may contain a
+// limited set of HTML tags. HTML is the sanitized rendering of a Markdown
+// message, it is set by the server and never read from requests.
+type MessageContent struct {
+	Text   string        `bson:"text" json:"text"`
+	Format MessageFormat `bson:"format,omitempty" json:"format,omitempty"`
+	HTML   string        `bson:"html,omitempty" json:"html,omitempty"`
+}
+
+func (c MessageContent) Validate() error {
+	if strings.TrimSpace(c.Text) == "" {
+		return errors.New("Message text cannot be empty")
+	}
+	if utf8.RuneCountInString(c.Text) > MaxMessageLength {
+		return fmt.Errorf("Message can have maximum %d characters", MaxMessageLength)
+	}
+	switch c.Format {
+	case "", MessageFormatPlain, MessageFormatMarkdown:
+	default:
+		return fmt.Errorf("Message format must be '%s' or '%s'", MessageFormatPlain, MessageFormatMarkdown)
+	}
+	return nil
+}
+
+func (c MessageContent) Render() interface{} {
+	return c
+}
+
+// Upload refers to an object in the private upload bucket. URL is a short
+// lived download link minted when the content is shown, it is not stored.
+// Items created before uploads were private only have a stored URL.
+type Upload struct {
+	ObjectKey string `bson:"object_key,omitempty" json:"object_key"`
+	URL       string `bson:"url,omitempty" json:"url,omitempty"`
+}
+
+// UploadedContent is implemented by the content types that refer to an
+// uploaded object.
+type UploadedContent interface {
+	GetUpload() *Upload
+}
+
+func (u *Upload) GetUpload() *Upload {
+	return u
+}
+
+// UploadedContentTypes returns the registered content types whose content
+// refers to an uploaded object, sorted by name.
+func UploadedContentTypes() []ContentType {
+	var types []ContentType
+	for contentType, newDetail := range contentTypes {
+		if _, ok := newDetail().(UploadedContent); ok {
+			types = append(types, contentType)
+		}
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+func (u Upload) validate(contentType ContentType) error {
+	if u.ObjectKey == "" && u.URL == "" {
+		return fmt.Errorf("Please upload the %s first", contentType)
+	}
+	return nil
+}
+
+type ImageContent struct {
+	Upload  `bson:",inline"`
+	Caption string `bson:"caption,omitempty" json:"caption,omitempty"`
+	AltText string `bson:"alt_text,omitempty" json:"alt_text,omitempty"`
+}
+
+func (c ImageContent) Validate() error {
+	return c.Upload.validate(ContentTypeImage)
+}
+
+func (c ImageContent) Render() interface{} {
+	return c
+}
+
+type VideoContent struct {
+	Upload  `bson:",inline"`
+	Caption string `bson:"caption,omitempty" json:"caption,omitempty"`
+}
+
+func (c VideoContent) Validate() error {
+	return c.Upload.validate(ContentTypeVideo)
+}
+
+func (c VideoContent) Render() interface{} {
+	return c
+}
+
+// AudioContent is a recorded voice note. Transcript is an optional caption
+// for listeners who cannot play the recording.
+type AudioContent struct {
+	Upload          `bson:",inline"`
+	DurationSeconds float64 `bson:"duration_seconds,omitempty" json:"duration_seconds,omitempty"`
+	Transcript      string  `bson:"transcript,omitempty" json:"transcript,omitempty"`
+}
+
+func (c AudioContent) Validate() error {
+	if err := c.Upload.validate(ContentTypeAudio); err != nil {
+		return err
+	}
+	if c.DurationSeconds < 0 {
+		return errors.New("Audio duration cannot be negative")
+	}
+	return nil
+}
+
+func (c AudioContent) Render() interface{} {
+	return c
+}
+
+// FileContent is an uploaded document such as a PDF, a spreadsheet or an
+// archive.
+type FileContent struct {
+	Upload   `bson:",inline"`
+	Name     string `bson:"name" json:"name"`
+	Size     int64  `bson:"size" json:"size"`
+	MimeType string `bson:"mime_type,omitempty" json:"mime_type,omitempty"`
+}
+
+func (c FileContent) Validate() error {
+	if c.Name == "" {
+		return errors.New("File name cannot be empty")
+	}
+	if err := c.Upload.validate(ContentTypeFile); err != nil {
+		return err
+	}
+	if c.Size <= 0 {
+		return errors.New("File size must be greater than zero")
+	}
+	return nil
+}
+
+func (c FileContent) Render() interface{} {
+	return c
+}
+
+func (ci ContentItem) Validate() error {
+	detail, ok := ci.Content.(ContentDetail)
+	if !ok {
+		return fmt.Errorf("Invalid %s content format", ci.Type)
+	}
+	return detail.Validate()
+}
+
+func (ci ContentItem) MarshalJSON() ([]byte, error) {
+	type contentItem ContentItem
+	out := contentItem(ci)
+	if detail, ok := ci.Content.(ContentDetail); ok {
+		out.Content = detail.Render()
+	}
+	return json.Marshal(out)
+}
+
+func (ci *ContentItem) UnmarshalJSON(data []byte) error {
+	temp := struct {
+		Type     ContentType     `json:"type"`
+		Content  json.RawMessage `json:"content"`
+		RevealAt *time.Time      `json:"reveal_at"`
+	}{}
+
+	if err := json.Unmarshal(data, &temp); err != nil {
+		return err
+	}
+
+	ci.Type = temp.Type
+	ci.RevealAt = temp.RevealAt
+
+	newDetail, ok := contentTypes[ci.Type]
+	if !ok {
+		return fmt.Errorf("unknown content type: %s", ci.Type)
+	}
+
+	detail := newDetail()
+	if err := json.Unmarshal(temp.Content, detail); err != nil {
+		return fmt.Errorf("invalid %s content: %w", ci.Type, err)
+	}
+	ci.Content = detail
+
+	return nil
+}
+
+func (ci *ContentItem) UnmarshalBSON(data []byte) error {
+	temp := struct {
+		Type          ContentType   `bson:"type"`
+		Content       bson.RawValue `bson:"content"`
+		ContributedBy string        `bson:"contributed_by,omitempty"`
+		RevealAt      *time.Time    `bson:"reveal_at,omitempty"`
+	}{}
+
+	if err := bson.Unmarshal(data, &temp); err != nil {
+		return err
+	}
+
+	ci.Type = temp.Type
+	ci.ContributedBy = temp.ContributedBy
+	ci.RevealAt = temp.RevealAt
+
+	newDetail, ok := contentTypes[ci.Type]
+	if !ok {
+		// Keep items of unknown types readable instead of failing the whole
+		// capsule.
+		var raw bson.M
+		if err := temp.Content.Unmarshal(&raw); err != nil {
+			return fmt.Errorf("invalid %s content: %w", ci.Type, err)
+		}
+		ci.Content = raw
+		return nil
+	}
+
+	detail := newDetail()
+	if err := temp.Content.Unmarshal(detail); err != nil {
+		return fmt.Errorf("invalid %s content: %w", ci.Type, err)
+	}
+	ci.Content = detail
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// DeadMansSwitch opens a capsule when its creator stops checking in. The
+// creator has CheckInDays to check in again, after the deadline the capsule
+// waits GraceDays more before it opens.
+type DeadMansSwitch struct {
+	CheckInDays int       `bson:"check_in_days" json:"check_in_days"`
+	GraceDays   int       `bson:"grace_days" json:"grace_days"`
+	LastCheckIn time.Time `bson:"last_check_in" json:"last_check_in"`
+	RemindAt    time.Time `bson:"remind_at" json:"-"`
+	Reminded    bool      `bson:"reminded" json:"-"`
+	OpensAt     time.Time `bson:"opens_at" json:"opens_at"`
+}
+
+func (d *DeadMansSwitch) Deadline() time.Time {
+	return d.LastCheckIn.AddDate(0, 0, d.CheckInDays)
+}
+
+// CheckIn restarts the switch at now. The creator is reminded reminderLead
+// before the next deadline, or right away if the interval is shorter.
+func (d *DeadMansSwitch) CheckIn(now time.Time, reminderLead time.Duration) {
+	d.LastCheckIn = now
+	deadline := d.Deadline()
+
+	d.RemindAt = deadline.Add(-reminderLead)
+	if d.RemindAt.Before(now) {
+		d.RemindAt = now
+	}
+	d.Reminded = false
+	d.OpensAt = deadline.AddDate(0, 0, d.GraceDays)
+}
@@ -0,0 +1,61 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeadMansSwitchCheckIn(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	sw := DeadMansSwitch{CheckInDays: 30, GraceDays: 7, Reminded: true}
+	sw.CheckIn(now, 48*time.Hour)
+
+	if want := now.AddDate(0, 0, 30); !sw.Deadline().Equal(want) {
+		t.Errorf("Deadline = %v, want %v", sw.Deadline(), want)
+	}
+	if want := now.AddDate(0, 0, 28); !sw.RemindAt.Equal(want) {
+		t.Errorf("RemindAt = %v, want %v", sw.RemindAt, want)
+	}
+	if want := now.AddDate(0, 0, 37); !sw.OpensAt.Equal(want) {
+		t.Errorf("OpensAt = %v, want %v", sw.OpensAt, want)
+	}
+	if sw.Reminded {
+		t.Error("checking in kept the reminder marked as sent")
+	}
+
+	// The lead is longer than the interval, the reminder is due right away.
+	short := DeadMansSwitch{CheckInDays: 1}
+	short.CheckIn(now, 48*time.Hour)
+	if !short.RemindAt.Equal(now) {
+		t.Errorf("RemindAt = %v, want %v", short.RemindAt, now)
+	}
+}
+
+func TestReadyToOpenWithDeadMansSwitch(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	sw := &DeadMansSwitch{CheckInDays: 7, GraceDays: 2}
+	sw.CheckIn(now, 0)
+
+	c := Capsule{Status: CapsuleStatusSealed, DeadMansSwitch: sw}
+	tests := []struct {
+		name string
+		at   time.Time
+		want bool
+	}{
+		{"before the deadline", now.AddDate(0, 0, 6), false},
+		{"during the grace period", now.AddDate(0, 0, 8), false},
+		{"after the grace period", now.AddDate(0, 0, 9), true},
+	}
+
+	for _, tt := range tests {
+		if got := c.ReadyToOpen(tt.at); got != tt.want {
+			t.Errorf("%s: ReadyToOpen = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	c.Status = CapsuleStatusDraft
+	if c.ReadyToOpen(now.AddDate(1, 0, 0)) {
+		t.Error("a draft is ready to open")
+	}
+}
@@ -0,0 +1,64 @@
+package model
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+const earthRadiusMeters = 6371000
+
+// Geofence restricts the content of an opened capsule to requests made from
+// within RadiusMeters of the given point.
+type Geofence struct {
+	Latitude     float64 `bson:"lat" json:"lat"`
+	Longitude    float64 `bson:"lng" json:"lng"`
+	RadiusMeters float64 `bson:"radius_meters" json:"radius_meters"`
+}
+
+// LocationUnlock audits where a user was when they first opened a geofenced
+// capsule.
+type LocationUnlock struct {
+	UserID         string    `bson:"user_id" json:"user_id"`
+	Latitude       float64   `bson:"lat" json:"lat"`
+	Longitude      float64   `bson:"lng" json:"lng"`
+	DistanceMeters float64   `bson:"distance_meters" json:"distance_meters"`
+	UnlockedAt     time.Time `bson:"unlocked_at" json:"unlocked_at"`
+}
+
+func (g *Geofence) Validate() error {
+	if !validCoordinates(g.Latitude, g.Longitude) {
+		return errors.New("geofence coordinates are out of range")
+	}
+	if g.RadiusMeters <= 0 {
+		return errors.New("geofence radius must be positive")
+	}
+	return nil
+}
+
+// Contains reports whether the point lies inside the fence, along with its
+// distance from the fence's center.
+func (g *Geofence) Contains(lat, lng float64) (float64, bool) {
+	if !validCoordinates(lat, lng) {
+		return 0, false
+	}
+	distance := haversine(g.Latitude, g.Longitude, lat, lng)
+	return distance, distance <= g.RadiusMeters
+}
+
+func validCoordinates(lat, lng float64) bool {
+	return lat >= -90 && lat <= 90 && lng >= -180 && lng <= 180
+}
+
+// haversine returns the great-circle distance in meters between two points.
+func haversine(lat1, lng1, lat2, lng2 float64) float64 {
+	toRadians := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRadians(lat2 - lat1)
+	dLng := toRadians(lng2 - lng1)
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRadians(lat1))*math.Cos(toRadians(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+
+	return 2 * earthRadiusMeters * math.Asin(math.Sqrt(a))
+}
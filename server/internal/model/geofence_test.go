@@ -0,0 +1,72 @@
+package model
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHaversine(t *testing.T) {
+	tests := []struct {
+		name                   string
+		lat1, lng1, lat2, lng2 float64
+		want                   float64
+	}{
+		{"same point", 48.8584, 2.2945, 48.8584, 2.2945, 0},
+		{"Paris to London", 48.8566, 2.3522, 51.5074, -0.1278, 343_560},
+		{"one degree of latitude", 0, 0, 1, 0, 111_195},
+		{"across the antimeridian", 0, 179.5, 0, -179.5, 111_195},
+		{"antipodes", 0, 0, 0, 180, math.Pi * earthRadiusMeters},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := haversine(tt.lat1, tt.lng1, tt.lat2, tt.lng2)
+			if math.Abs(got-tt.want) > tt.want*0.001+1 {
+				t.Errorf("haversine = %.0f m, want %.0f m", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGeofenceContains(t *testing.T) {
+	// 500 m around the Eiffel Tower.
+	fence := Geofence{Latitude: 48.8584, Longitude: 2.2945, RadiusMeters: 500}
+
+	tests := []struct {
+		name     string
+		lat, lng float64
+		inside   bool
+	}{
+		{"center", 48.8584, 2.2945, true},
+		{"Champ de Mars", 48.8556, 2.2986, true},
+		{"Louvre", 48.8606, 2.3376, false},
+		{"invalid latitude", 91, 2.2945, false},
+		{"invalid longitude", 48.8584, 181, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, inside := fence.Contains(tt.lat, tt.lng); inside != tt.inside {
+				t.Errorf("Contains(%v, %v) = %v, want %v", tt.lat, tt.lng, inside, tt.inside)
+			}
+		})
+	}
+}
+
+func TestGeofenceValidate(t *testing.T) {
+	tests := []struct {
+		fence Geofence
+		valid bool
+	}{
+		{Geofence{Latitude: 48.8584, Longitude: 2.2945, RadiusMeters: 100}, true},
+		{Geofence{Latitude: -91, Longitude: 0, RadiusMeters: 100}, false},
+		{Geofence{Latitude: 0, Longitude: 200, RadiusMeters: 100}, false},
+		{Geofence{Latitude: 0, Longitude: 0, RadiusMeters: 0}, false},
+	}
+
+	for _, tt := range tests {
+		if err := tt.fence.Validate(); (err == nil) != tt.valid {
+			t.Errorf("Validate(%+v) = %v, want valid %v", tt.fence, err, tt.valid)
+		}
+	}
+}
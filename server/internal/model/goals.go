@@ -0,0 +1,139 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const MaxGoals = 20
+
+type GoalStatus string
+
+const (
+	GoalAchieved GoalStatus = "achieved"
+	GoalPartial  GoalStatus = "partial"
+	GoalMissed   GoalStatus = "missed"
+)
+
+// GoalsContent is a checklist of goals set when the capsule is created.
+// Once it opens every member assesses each goal for themselves.
+type GoalsContent struct {
+	Goals []Goal `bson:"goals" json:"goals"`
+}
+
+type Goal struct {
+	ID          primitive.ObjectID `bson:"id" json:"id"`
+	Title       string             `bson:"title" json:"title"`
+	Assessments []GoalAssessment   `bson:"assessments" json:"assessments"`
+}
+
+type GoalAssessment struct {
+	ParticipantEmail string     `bson:"participant_email" json:"participant_email"`
+	Status           GoalStatus `bson:"status" json:"status"`
+	Notes            string     `bson:"notes,omitempty" json:"notes,omitempty"`
+	AssessedAt       time.Time  `bson:"assessed_at" json:"assessed_at"`
+}
+
+// GoalsSummary counts the assessments of all goals of a capsule. Unassessed
+// is the number of goals nobody has assessed yet.
+type GoalsSummary struct {
+	Goals      int `json:"goals"`
+	Achieved   int `json:"achieved"`
+	Partial    int `json:"partial"`
+	Missed     int `json:"missed"`
+	Unassessed int `json:"unassessed"`
+}
+
+func (c GoalsContent) Validate() error {
+	if len(c.Goals) == 0 {
+		return errors.New("Please add at least one goal")
+	}
+
+	if len(c.Goals) > MaxGoals {
+		return fmt.Errorf("Goals checklist can hold maximum %d goals", MaxGoals)
+	}
+
+	for _, goal := range c.Goals {
+		if strings.TrimSpace(goal.Title) == "" {
+			return errors.New("Goal title cannot be empty")
+		}
+	}
+
+	return nil
+}
+
+func (c GoalsContent) Render() interface{} {
+	return c
+}
+
+func (s GoalStatus) Validate() error {
+	switch s {
+	case GoalAchieved, GoalPartial, GoalMissed:
+		return nil
+	}
+	return fmt.Errorf("Goal status must be '%s', '%s' or '%s'", GoalAchieved, GoalPartial, GoalMissed)
+}
+
+// FindGoal returns the goal with the given ID among the goals checklists of
+// items.
+func FindGoal(items []ContentItem, id primitive.ObjectID) (*ContentItem, *Goal) {
+	for i := range items {
+		goals, ok := items[i].Content.(*GoalsContent)
+		if !ok {
+			continue
+		}
+		for j := range goals.Goals {
+			if goals.Goals[j].ID == id {
+				return &items[i], &goals.Goals[j]
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (g *Goal) AssessmentBy(email string) *GoalAssessment {
+	for i := range g.Assessments {
+		if g.Assessments[i].ParticipantEmail == email {
+			return &g.Assessments[i]
+		}
+	}
+	return nil
+}
+
+// SummarizeGoals summarizes the goals checklists among items. It returns nil
+// when there are none.
+func SummarizeGoals(items []ContentItem) *GoalsSummary {
+	var summary *GoalsSummary
+	for _, item := range items {
+		goals, ok := item.Content.(*GoalsContent)
+		if !ok {
+			continue
+		}
+
+		if summary == nil {
+			summary = &GoalsSummary{}
+		}
+
+		for _, goal := range goals.Goals {
+			summary.Goals++
+			if len(goal.Assessments) == 0 {
+				summary.Unassessed++
+			}
+			for _, assessment := range goal.Assessments {
+				switch assessment.Status {
+				case GoalAchieved:
+					summary.Achieved++
+				case GoalPartial:
+					summary.Partial++
+				case GoalMissed:
+					summary.Missed++
+				}
+			}
+		}
+	}
+	return summary
+}
@@ -0,0 +1,40 @@
+package model
+
+import (
+	"errors"
+	"net/url"
+	"time"
+)
+
+// LinkContent is a web page. Preview is a snapshot of the page captured by
+// the server after the capsule is sealed, so the link stays recognizable
+// after the page is gone. It holds no image, an image of the page would be
+// loaded from its site by every viewer and is likely gone by the time the
+// capsule opens.
+type LinkContent struct {
+	URL     string       `bson:"url" json:"url"`
+	Preview *LinkPreview `bson:"preview,omitempty" json:"preview,omitempty"`
+}
+
+type LinkPreview struct {
+	Title       string    `bson:"title,omitempty" json:"title,omitempty"`
+	Description string    `bson:"description,omitempty" json:"description,omitempty"`
+	CapturedAt  time.Time `bson:"captured_at" json:"captured_at"`
+}
+
+func (c LinkContent) Validate() error {
+	if c.URL == "" {
+		return errors.New("Link URL cannot be empty")
+	}
+
+	u, err := url.Parse(c.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("Link must be a valid http or https URL")
+	}
+
+	return nil
+}
+
+func (c LinkContent) Render() interface{} {
+	return c
+}
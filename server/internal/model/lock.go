@@ -0,0 +1,49 @@
+package model
+
+import (
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PassphraseLock keeps the content of an opened capsule hidden until a user
+// answers the passphrase or riddle set by the creator. The hint is shown
+// while the capsule is sealed.
+type PassphraseLock struct {
+	// Passphrase is only read from requests, it is never stored.
+	Passphrase string   `bson:"-" json:"passphrase,omitempty"`
+	Hash       string   `bson:"hash" json:"-"`
+	Hint       string   `bson:"hint,omitempty" json:"hint,omitempty"`
+	UnlockedBy []string `bson:"unlocked_by" json:"-"`
+}
+
+// HashPassphrase replaces the plain passphrase with its bcrypt hash.
+func (l *PassphraseLock) HashPassphrase() error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(normalizePassphrase(l.Passphrase)), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	l.Hash = string(hash)
+	l.Passphrase = ""
+	return nil
+}
+
+func (l *PassphraseLock) Matches(passphrase string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(l.Hash), []byte(normalizePassphrase(passphrase))) == nil
+}
+
+func (l *PassphraseLock) IsUnlockedBy(userID string) bool {
+	for _, unlocked := range l.UnlockedBy {
+		if unlocked == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizePassphrase makes answers case and whitespace insensitive, riddle
+// answers rarely match the creator's spelling otherwise.
+func normalizePassphrase(passphrase string) string {
+	return strings.ToLower(strings.Join(strings.Fields(passphrase), " "))
+}
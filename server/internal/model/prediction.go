@@ -0,0 +1,145 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const MaxPredictionOptions = 10
+
+// PredictionContent is a question posed by the creator. Members answer it
+// until the capsule opens and mark which answers came true once it opens. Options is optional, without it answers are free text.
+type PredictionContent struct {
+	ID       primitive.ObjectID `bson:"id" json:"id"`
+	Question string             `bson:"question" json:"question"`
+	Options  []string           `bson:"options,omitempty" json:"options,omitempty"`
+	Answers  []PredictionAnswer `bson:"answers" json:"answers"`
+}
+
+type PredictionAnswer struct {
+	ParticipantEmail string    `bson:"participant_email" json:"participant_email"`
+	Answer           string    `bson:"answer" json:"answer"`
+	AnsweredAt       time.Time `bson:"answered_at" json:"answered_at"`
+	// CameTrue stays nil until a member marks the answer after opening.
+	CameTrue *bool `bson:"came_true,omitempty" json:"came_true"`
+}
+
+// PredictionScore counts how many of a member's answers came true.
+type PredictionScore struct {
+	ParticipantEmail string `json:"participant_email"`
+	Answered         int    `json:"answered"`
+	CameTrue         int    `json:"came_true"`
+}
+
+func (c PredictionContent) Validate() error {
+	if strings.TrimSpace(c.Question) == "" {
+		return errors.New("Prediction question cannot be empty")
+	}
+
+	if len(c.Options) == 1 {
+		return errors.New("Prediction needs at least 2 options")
+	}
+
+	if len(c.Options) > MaxPredictionOptions {
+		return fmt.Errorf("Prediction can have maximum %d options", MaxPredictionOptions)
+	}
+
+	seen := map[string]bool{}
+	for _, option := range c.Options {
+		option = strings.TrimSpace(option)
+		if option == "" {
+			return errors.New("Prediction options cannot be empty")
+		}
+		if seen[option] {
+			return fmt.Errorf("Duplicate prediction option: %s", option)
+		}
+		seen[option] = true
+	}
+
+	return nil
+}
+
+func (c PredictionContent) Render() interface{} {
+	return c
+}
+
+// ValidateAnswer checks an answer against the options of the prediction.
+func (c *PredictionContent) ValidateAnswer(answer string) error {
+	if strings.TrimSpace(answer) == "" {
+		return errors.New("Answer cannot be empty")
+	}
+
+	if len(c.Options) == 0 {
+		return nil
+	}
+
+	for _, option := range c.Options {
+		if option == answer {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("Answer must be one of: %s", strings.Join(c.Options, ", "))
+}
+
+func (c *PredictionContent) AnswerBy(email string) *PredictionAnswer {
+	for i := range c.Answers {
+		if c.Answers[i].ParticipantEmail == email {
+			return &c.Answers[i]
+		}
+	}
+	return nil
+}
+
+// FindPrediction returns the prediction with the given ID among items.
+func FindPrediction(items []ContentItem, id primitive.ObjectID) (*ContentItem, *PredictionContent) {
+	for i := range items {
+		if prediction, ok := items[i].Content.(*PredictionContent); ok && prediction.ID == id {
+			return &items[i], prediction
+		}
+	}
+	return nil, nil
+}
+
+// ScorePredictions tallies the answers of every member across the
+// predictions among items, best score first.
+func ScorePredictions(items []ContentItem) []PredictionScore {
+	scores := map[string]*PredictionScore{}
+	for _, item := range items {
+		prediction, ok := item.Content.(*PredictionContent)
+		if !ok {
+			continue
+		}
+
+		for _, answer := range prediction.Answers {
+			score, ok := scores[answer.ParticipantEmail]
+			if !ok {
+				score = &PredictionScore{ParticipantEmail: answer.ParticipantEmail}
+				scores[answer.ParticipantEmail] = score
+			}
+			score.Answered++
+			if answer.CameTrue != nil && *answer.CameTrue {
+				score.CameTrue++
+			}
+		}
+	}
+
+	result := make([]PredictionScore, 0, len(scores))
+	for _, score := range scores {
+		result = append(result, *score)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].CameTrue != result[j].CameTrue {
+			return result[i].CameTrue > result[j].CameTrue
+		}
+		return result[i].ParticipantEmail < result[j].ParticipantEmail
+	})
+
+	return result
+}
@@ -0,0 +1,23 @@
+package model
+
+// Quorum makes a capsule open only once Required of its participants have
+// turned their key. With RequireDate the scheduled open date has to pass as
+// well, otherwise the date is ignored.
+type Quorum struct {
+	Required    int      `bson:"required" json:"required"`
+	RequireDate bool     `bson:"require_date" json:"require_date"`
+	Approvals   []string `bson:"approvals" json:"approvals"`
+}
+
+func (q *Quorum) HasApproved(email string) bool {
+	for _, approval := range q.Approvals {
+		if approval == email {
+			return true
+		}
+	}
+	return false
+}
+
+func (q *Quorum) Reached() bool {
+	return len(q.Approvals) >= q.Required
+}
@@ -0,0 +1,135 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type RecurrenceFrequency string
+
+const (
+	RecurrenceYearly  RecurrenceFrequency = "yearly"
+	RecurrenceMonthly RecurrenceFrequency = "monthly"
+	RecurrenceWeekly  RecurrenceFrequency = "weekly"
+)
+
+// Recurrence describes how often a capsule repeats. It can be sent as an
+// object or as a subset of an iCalendar RRULE, e.g. "FREQ=YEARLY;INTERVAL=1".
+type Recurrence struct {
+	Frequency RecurrenceFrequency `bson:"frequency" json:"frequency"`
+	Interval  int                 `bson:"interval,omitempty" json:"interval,omitempty"`
+	Until     *time.Time          `bson:"until,omitempty" json:"until,omitempty"`
+}
+
+func (r *Recurrence) Validate() error {
+	switch r.Frequency {
+	case RecurrenceYearly, RecurrenceMonthly, RecurrenceWeekly:
+	default:
+		return fmt.Errorf("unsupported recurrence frequency: %s", r.Frequency)
+	}
+
+	if r.Interval < 0 {
+		return errors.New("recurrence interval cannot be negative")
+	}
+
+	return nil
+}
+
+// Next returns the occurrence following t. It reports false once the
+// recurrence has passed its Until date.
+func (r *Recurrence) Next(t time.Time) (time.Time, bool) {
+	interval := r.Interval
+	if interval == 0 {
+		interval = 1
+	}
+
+	var next time.Time
+	switch r.Frequency {
+	case RecurrenceYearly:
+		next = t.AddDate(interval, 0, 0)
+	case RecurrenceMonthly:
+		next = t.AddDate(0, interval, 0)
+	case RecurrenceWeekly:
+		next = t.AddDate(0, 0, 7*interval)
+	default:
+		return time.Time{}, false
+	}
+
+	if r.Until != nil && next.After(*r.Until) {
+		return time.Time{}, false
+	}
+
+	return next, true
+}
+
+func (r *Recurrence) UnmarshalJSON(data []byte) error {
+	var rule string
+	if err := json.Unmarshal(data, &rule); err == nil {
+		parsed, err := ParseRRule(rule)
+		if err != nil {
+			return err
+		}
+		*r = *parsed
+		return nil
+	}
+
+	type recurrence Recurrence
+	var temp recurrence
+	if err := json.Unmarshal(data, &temp); err != nil {
+		return err
+	}
+	*r = Recurrence(temp)
+
+	return nil
+}
+
+// ParseRRule parses the FREQ, INTERVAL and UNTIL parts of an RRULE. A bare
+// frequency such as "yearly" is accepted as well.
+func ParseRRule(rule string) (*Recurrence, error) {
+	rule = strings.TrimPrefix(strings.TrimSpace(rule), "RRULE:")
+	if !strings.Contains(rule, "=") {
+		return &Recurrence{Frequency: RecurrenceFrequency(strings.ToLower(rule))}, nil
+	}
+
+	var r Recurrence
+	for _, part := range strings.Split(rule, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid recurrence rule part: %s", part)
+		}
+
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			r.Frequency = RecurrenceFrequency(strings.ToLower(value))
+		case "INTERVAL":
+			interval, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid recurrence interval: %s", value)
+			}
+			r.Interval = interval
+		case "UNTIL":
+			until, err := parseRRuleTime(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid recurrence until: %s", value)
+			}
+			r.Until = &until
+		default:
+			return nil, fmt.Errorf("unsupported recurrence rule part: %s", key)
+		}
+	}
+
+	return &r, nil
+}
+
+func parseRRuleTime(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102", time.RFC3339} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.New("unknown time format")
+}
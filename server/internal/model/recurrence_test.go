@@ -0,0 +1,95 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParseRRule(t *testing.T) {
+	until := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		rule    string
+		want    Recurrence
+		wantErr bool
+	}{
+		{rule: "FREQ=YEARLY", want: Recurrence{Frequency: RecurrenceYearly}},
+		{rule: "RRULE:FREQ=MONTHLY;INTERVAL=3", want: Recurrence{Frequency: RecurrenceMonthly, Interval: 3}},
+		{rule: "freq=weekly;until=20300101T000000Z", want: Recurrence{Frequency: RecurrenceWeekly, Until: &until}},
+		{rule: "FREQ=YEARLY;UNTIL=20300101", want: Recurrence{Frequency: RecurrenceYearly, Until: &until}},
+		{rule: "yearly", want: Recurrence{Frequency: RecurrenceYearly}},
+		{rule: "FREQ=YEARLY;INTERVAL=often", wantErr: true},
+		{rule: "FREQ=YEARLY;UNTIL=someday", wantErr: true},
+		{rule: "FREQ=DAILY;BYHOUR=9", wantErr: true},
+		{rule: "FREQ=YEARLY;COUNT", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			got, err := ParseRRule(tt.rule)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseRRule = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Frequency != tt.want.Frequency || got.Interval != tt.want.Interval ||
+				(got.Until == nil) != (tt.want.Until == nil) || (got.Until != nil && !got.Until.Equal(*tt.want.Until)) {
+				t.Errorf("ParseRRule = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecurrenceNext(t *testing.T) {
+	start := time.Date(2025, 3, 15, 9, 0, 0, 0, time.UTC)
+	until := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		recurrence Recurrence
+		want       time.Time
+		ok         bool
+	}{
+		{"yearly", Recurrence{Frequency: RecurrenceYearly}, time.Date(2026, 3, 15, 9, 0, 0, 0, time.UTC), true},
+		{"every two years", Recurrence{Frequency: RecurrenceYearly, Interval: 2}, time.Date(2027, 3, 15, 9, 0, 0, 0, time.UTC), true},
+		{"monthly", Recurrence{Frequency: RecurrenceMonthly}, time.Date(2025, 4, 15, 9, 0, 0, 0, time.UTC), true},
+		{"every two weeks", Recurrence{Frequency: RecurrenceWeekly, Interval: 2}, time.Date(2025, 3, 29, 9, 0, 0, 0, time.UTC), true},
+		{"before until", Recurrence{Frequency: RecurrenceWeekly, Until: &until}, time.Date(2025, 3, 22, 9, 0, 0, 0, time.UTC), true},
+		{"past until", Recurrence{Frequency: RecurrenceMonthly, Until: &until}, time.Time{}, false},
+		{"unknown frequency", Recurrence{Frequency: "daily"}, time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.recurrence.Next(start)
+			if ok != tt.ok || !got.Equal(tt.want) {
+				t.Errorf("Next = %v, %v, want %v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestRecurrenceUnmarshalJSON(t *testing.T) {
+	var fromRule, fromObject Recurrence
+	if err := json.Unmarshal([]byte(`"FREQ=MONTHLY;INTERVAL=6"`), &fromRule); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(`{"frequency":"monthly","interval":6}`), &fromObject); err != nil {
+		t.Fatal(err)
+	}
+
+	want := Recurrence{Frequency: RecurrenceMonthly, Interval: 6}
+	if fromRule != want || fromObject != want {
+		t.Errorf("decoded %+v and %+v, want %+v", fromRule, fromObject, want)
+	}
+
+	var invalid Recurrence
+	if err := json.Unmarshal([]byte(`"FREQ=MONTHLY;INTERVAL=x"`), &invalid); err == nil {
+		t.Error("invalid rule was decoded")
+	}
+}
@@ -0,0 +1,102 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type UploadSessionStatus string
+
+const (
+	UploadSessionUploading UploadSessionStatus = "uploading"
+	// UploadSessionAssembled marks a tus upload whose file is complete in the
+	// object store but not added to its capsule yet.
+	UploadSessionAssembled UploadSessionStatus = "assembled"
+	UploadSessionCompleted UploadSessionStatus = "completed"
+	UploadSessionAborted   UploadSessionStatus = "aborted"
+)
+
+// UploadSession tracks a multipart upload, so a client can resume it after
+// losing its connection. The file is split into PartCount parts of PartSize
+// bytes, only the last part may be smaller.
+//
+// Tus uploads are written by the server. Their data that does not fill a
+// part yet is kept as a pending object of TailSize bytes, and the finished
+// file is added to the capsule CapsuleID.
+type UploadSession struct {
+	ID     primitive.ObjectID `bson:"_id" json:"id"`
+	UserID string             `bson:"user_id" json:"-"`
+	Tus    bool               `bson:"tus,omitempty" json:"-"`
+	// UploadID identifies the upload in the object store.
+	UploadID    string              `bson:"upload_id" json:"-"`
+	ObjectKey   string              `bson:"object_key" json:"object_key"`
+	ContentType ContentType         `bson:"content_type" json:"content_type"`
+	FileName    string              `bson:"file_name" json:"file_name"`
+	FileType    string              `bson:"file_type" json:"file_type"`
+	FileSize    int64               `bson:"file_size" json:"file_size"`
+	PartSize    int64               `bson:"part_size" json:"part_size"`
+	PartCount   int32               `bson:"part_count" json:"part_count"`
+	Parts       []UploadPart        `bson:"parts" json:"parts"`
+	TailSize    int64               `bson:"tail_size,omitempty" json:"-"`
+	CapsuleID   *primitive.ObjectID `bson:"capsule_id,omitempty" json:"capsule_id,omitempty"`
+	Status      UploadSessionStatus `bson:"status" json:"status"`
+	CreatedAt   time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time           `bson:"updated_at" json:"updated_at"`
+	ExpiresAt   time.Time           `bson:"expires_at" json:"expires_at"`
+}
+
+type UploadPart struct {
+	Number int32  `bson:"number" json:"number"`
+	ETag   string `bson:"etag" json:"-"`
+	Size   int64  `bson:"size" json:"size"`
+}
+
+// PartSizeOf returns the size of part number, numbered from 1.
+func (s *UploadSession) PartSizeOf(number int32) int64 {
+	if number == s.PartCount {
+		return s.FileSize - int64(s.PartCount-1)*s.PartSize
+	}
+	return s.PartSize
+}
+
+// UploadedBytes returns the size of the parts uploaded so far.
+func (s *UploadSession) UploadedBytes() int64 {
+	var n int64
+	for _, part := range s.Parts {
+		n += part.Size
+	}
+	return n
+}
+
+// Offset returns the number of bytes of a tus upload received so far.
+func (s *UploadSession) Offset() int64 {
+	return s.UploadedBytes() + s.TailSize
+}
+
+// TailName names the pending tail of a tus upload. The name changes with
+// every write, so a tail is never overwritten by a request that loses the
+// race to record its progress.
+func (s *UploadSession) TailName() string {
+	return fmt.Sprintf("%s/%d-%d", s.ID.Hex(), len(s.Parts)+1, s.TailSize)
+}
+
+// MissingParts returns the numbers of the parts that have not been uploaded
+// completely yet.
+func (s *UploadSession) MissingParts() []int32 {
+	uploaded := map[int32]bool{}
+	for _, part := range s.Parts {
+		if part.Size == s.PartSizeOf(part.Number) {
+			uploaded[part.Number] = true
+		}
+	}
+
+	missing := []int32{}
+	for number := int32(1); number <= s.PartCount; number++ {
+		if !uploaded[number] {
+			missing = append(missing, number)
+		}
+	}
+	return missing
+}
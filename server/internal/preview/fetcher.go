@@ -0,0 +1,19 @@
+// Package preview captures snapshots of web pages for link content items.
+package preview
+
+import (
+	"context"
+	"errors"
+
+	"github.com/pateldivyesh1323/futflare/server/internal/model"
+)
+
+var (
+	ErrForbiddenAddress   = errors.New("link points to a forbidden address")
+	ErrUnsupportedContent = errors.New("link does not point to an HTML page")
+)
+
+// Fetcher captures the preview of the page at a URL.
+type Fetcher interface {
+	Fetch(ctx context.Context, rawURL string) (*model.LinkPreview, error)
+}
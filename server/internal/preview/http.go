@@ -0,0 +1,255 @@
+package preview
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/pateldivyesh1323/futflare/server/internal/model"
+)
+
+const (
+	DefaultTimeout  = 5 * time.Second
+	DefaultMaxBytes = 1 << 20
+
+	maxRedirects         = 5
+	maxTitleLength       = 300
+	maxDescriptionLength = 1000
+)
+
+// blockedNetworks are ranges that net.IP has no predicate for but that must
+// not be reachable from user supplied links either.
+var blockedNetworks = mustParseCIDRs(
+	"0.0.0.0/8",
+	"100.64.0.0/10",
+	"192.0.0.0/24",
+	"198.18.0.0/15",
+	"240.0.0.0/4",
+	"64:ff9b::/96",
+)
+
+// HTTPFetcher fetches pages over HTTP. Only the public ports 80 and 443 of
+// public addresses are dialed, the check runs on every connection so
+// redirects and DNS rebinding cannot reach internal services.
+type HTTPFetcher struct {
+	client   *http.Client
+	maxBytes int64
+}
+
+// NewHTTPFetcher returns a fetcher that gives up on a page after timeout and
+// reads at most maxBytes of it.
+func NewHTTPFetcher(timeout time.Duration, maxBytes int64) *HTTPFetcher {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: checkAddress,
+	}
+
+	transport := &http.Transport{
+		// A proxy would dial the target on our behalf and bypass the check.
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   timeout,
+		ResponseHeaderTimeout: timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+	}
+
+	return &HTTPFetcher{
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   timeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= maxRedirects {
+					return errors.New("too many redirects")
+				}
+				return checkURL(req.URL)
+			},
+		},
+		maxBytes: maxBytes,
+	}
+}
+
+func (f *HTTPFetcher) Fetch(ctx context.Context, rawURL string) (*model.LinkPreview, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkURL(u); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	req.Header.Set("User-Agent", "FutflareLinkPreview/1.0")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, ErrUnsupportedContent
+	}
+
+	preview := parseHead(io.LimitReader(resp.Body, f.maxBytes))
+	preview.CapturedAt = time.Now()
+
+	return preview, nil
+}
+
+// parseHead reads the title and description of a page from its Open Graph
+// tags, falling back to the title and description elements.
+func parseHead(r io.Reader) *model.LinkPreview {
+	var preview model.LinkPreview
+	var title, description string
+
+	z := html.NewTokenizer(r)
+tokens:
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+
+		token := z.Token()
+		if tt == html.EndTagToken && token.DataAtom == atom.Head {
+			break
+		}
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+
+		switch token.DataAtom {
+		case atom.Body:
+			// Pages without a head end their metadata at the body.
+			break tokens
+		case atom.Title:
+			if z.Next() == html.TextToken && title == "" {
+				title = string(z.Text())
+			}
+		case atom.Meta:
+			key := strings.ToLower(attr(token, "property"))
+			if key == "" {
+				key = strings.ToLower(attr(token, "name"))
+			}
+			content := attr(token, "content")
+
+			switch key {
+			case "og:title":
+				preview.Title = content
+			case "og:description":
+				preview.Description = content
+			case "description":
+				description = content
+			}
+		}
+	}
+
+	if preview.Title == "" {
+		preview.Title = title
+	}
+	if preview.Description == "" {
+		preview.Description = description
+	}
+
+	preview.Title = truncate(strings.TrimSpace(preview.Title), maxTitleLength)
+	preview.Description = truncate(strings.TrimSpace(preview.Description), maxDescriptionLength)
+
+	return &preview
+}
+
+func attr(token html.Token, key string) string {
+	for _, a := range token.Attr {
+		if strings.EqualFold(a.Key, key) {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func truncate(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return string([]rune(s)[:max])
+}
+
+func checkURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return errors.New("link has no host")
+	}
+	return nil
+}
+
+// checkAddress runs right before a connection is made, after the host name
+// has been resolved.
+func checkAddress(network, address string, _ syscall.RawConn) error {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	if port != "80" && port != "443" {
+		return ErrForbiddenAddress
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || !isPublic(ip) {
+		return ErrForbiddenAddress
+	}
+
+	return nil
+}
+
+func isPublic(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
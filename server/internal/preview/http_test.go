@@ -0,0 +1,141 @@
+package preview
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCheckAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		allowed bool
+	}{
+		{"93.184.216.34:443", true},
+		{"93.184.216.34:80", true},
+		{"[2606:2800:220:1:248:1893:25c8:1946]:443", true},
+		{"93.184.216.34:8080", false},
+		{"127.0.0.1:80", false},
+		{"10.0.0.1:443", false},
+		{"172.16.5.4:443", false},
+		{"192.168.1.1:80", false},
+		{"169.254.169.254:80", false},
+		{"100.64.0.1:80", false},
+		{"0.0.0.0:80", false},
+		{"[::1]:443", false},
+		{"[::ffff:127.0.0.1]:80", false},
+		{"[fd00::1]:443", false},
+		{"[fe80::1]:443", false},
+		{"[64:ff9b::7f00:1]:443", false},
+		{"localhost:80", false},
+	}
+
+	for _, tt := range tests {
+		err := checkAddress("tcp", tt.address, nil)
+		if tt.allowed && err != nil {
+			t.Errorf("checkAddress(%s) = %v, want it allowed", tt.address, err)
+		}
+		if !tt.allowed && err != ErrForbiddenAddress {
+			t.Errorf("checkAddress(%s) = %v, want ErrForbiddenAddress", tt.address, err)
+		}
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		allowed bool
+	}{
+		{"https://example.com/page", true},
+		{"http://example.com", true},
+		{"ftp://example.com/file", false},
+		{"file:///etc/passwd", false},
+		{"gopher://example.com", false},
+		{"http:///path", false},
+	}
+
+	for _, tt := range tests {
+		u, err := url.Parse(tt.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := checkURL(u); (err == nil) != tt.allowed {
+			t.Errorf("checkURL(%s) = %v, want allowed %v", tt.url, err, tt.allowed)
+		}
+	}
+}
+
+func TestFetchRefusesInternalAddresses(t *testing.T) {
+	requested := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = true
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<title>Internal</title>"))
+	}))
+	defer server.Close()
+
+	_, err := NewHTTPFetcher(DefaultTimeout, DefaultMaxBytes).Fetch(context.Background(), server.URL)
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Errorf("Fetch(%s) = %v, want ErrForbiddenAddress", server.URL, err)
+	}
+	if requested {
+		t.Error("the internal server was requested")
+	}
+}
+
+func TestParseHead(t *testing.T) {
+	tests := []struct {
+		name        string
+		page        string
+		title       string
+		description string
+	}{
+		{
+			name: "open graph",
+			page: `<html><head><title>Page</title>
+				<meta property="og:title" content="Graph title">
+				<meta property="og:description" content="Graph description">
+				<meta name="description" content="Plain description">
+				<meta property="og:image" content="https://example.com/image.png">
+				</head><body>Body</body></html>`,
+			title:       "Graph title",
+			description: "Graph description",
+		},
+		{
+			name:        "fallback",
+			page:        `<html><head><title> Page </title><meta name="Description" content="Plain description"></head></html>`,
+			title:       "Page",
+			description: "Plain description",
+		},
+		{
+			name:  "metadata ends at the body",
+			page:  `<title>Page</title><body><meta name="description" content="In the body"></body>`,
+			title: "Page",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			preview := parseHead(strings.NewReader(tt.page))
+			if preview.Title != tt.title || preview.Description != tt.description {
+				t.Errorf("parseHead = %q, %q, want %q, %q", preview.Title, preview.Description, tt.title, tt.description)
+			}
+		})
+	}
+}
+
+func TestParseHeadTruncates(t *testing.T) {
+	long := strings.Repeat("é", maxDescriptionLength+10)
+	preview := parseHead(strings.NewReader(`<title>` + long + `</title><meta name="description" content="` + long + `">`))
+
+	if n := len([]rune(preview.Title)); n != maxTitleLength {
+		t.Errorf("title has %d runes, want %d", n, maxTitleLength)
+	}
+	if n := len([]rune(preview.Description)); n != maxDescriptionLength {
+		t.Errorf("description has %d runes, want %d", n, maxDescriptionLength)
+	}
+}
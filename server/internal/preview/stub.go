@@ -0,0 +1,32 @@
+package preview
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/pateldivyesh1323/futflare/server/internal/model"
+)
+
+// StubFetcher returns canned previews instead of fetching pages. URLs that
+// are not in Previews fail with Err, or with an error when Err is nil.
+type StubFetcher struct {
+	Previews map[string]model.LinkPreview
+	Err      error
+}
+
+func (f StubFetcher) Fetch(ctx context.Context, rawURL string) (*model.LinkPreview, error) {
+	preview, ok := f.Previews[rawURL]
+	if !ok {
+		if f.Err != nil {
+			return nil, f.Err
+		}
+		return nil, errors.New("no preview for " + rawURL)
+	}
+
+	if preview.CapturedAt.IsZero() {
+		preview.CapturedAt = time.Now()
+	}
+
+	return &preview, nil
+}
@@ -0,0 +1,87 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter counts attempts per key in a fixed window. Once a key reaches max
+// attempts it is blocked until the window that started with its first
+// attempt has passed. A successful attempt resets the key.
+type Limiter struct {
+	mu        sync.Mutex
+	max       int
+	window    time.Duration
+	attempts  map[string]*entry
+	lastSweep time.Time
+}
+
+type entry struct {
+	count int
+	start time.Time
+}
+
+func New(max int, window time.Duration) *Limiter {
+	return &Limiter{
+		max:       max,
+		window:    window,
+		attempts:  make(map[string]*entry),
+		lastSweep: time.Now(),
+	}
+}
+
+// Attempt reports whether key may make another attempt and counts it.
+// Checking and counting happen together, so concurrent attempts cannot
+// exceed the limit. Call Reset once the attempt succeeded.
+func (l *Limiter) Attempt(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	e := l.current(key, now)
+	if e == nil {
+		e = &entry{start: now}
+		l.attempts[key] = e
+	}
+	if e.count >= l.max {
+		return false
+	}
+	e.count++
+	return true
+}
+
+func (l *Limiter) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.attempts, key)
+}
+
+func (l *Limiter) current(key string, now time.Time) *entry {
+	e, ok := l.attempts[key]
+	if !ok {
+		return nil
+	}
+	if now.Sub(e.start) >= l.window {
+		delete(l.attempts, key)
+		return nil
+	}
+	return e
+}
+
+// sweep evicts the entries whose window has passed, at most once per window,
+// so keys that are never used again do not pile up.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.window {
+		return
+	}
+	l.lastSweep = now
+
+	for key, e := range l.attempts {
+		if now.Sub(e.start) >= l.window {
+			delete(l.attempts, key)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLimiterLocksOut(t *testing.T) {
+	l := New(3, time.Hour)
+
+	for i := 0; i < 3; i++ {
+		if !l.Attempt("user:capsule") {
+			t.Fatalf("attempt %d was refused", i+1)
+		}
+	}
+	if l.Attempt("user:capsule") {
+		t.Error("attempt past the limit was allowed")
+	}
+	if !l.Attempt("other:capsule") {
+		t.Error("another key was locked out")
+	}
+
+	l.Reset("user:capsule")
+	if !l.Attempt("user:capsule") {
+		t.Error("attempt after Reset was refused")
+	}
+}
+
+func TestLimiterCountsConcurrentAttempts(t *testing.T) {
+	l := New(5, time.Hour)
+
+	var wg sync.WaitGroup
+	var allowed atomic.Int32
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if l.Attempt("user:capsule") {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := allowed.Load(); n != 5 {
+		t.Errorf("%d concurrent attempts were allowed, want 5", n)
+	}
+}
+
+func TestLimiterWindowExpires(t *testing.T) {
+	window := 20 * time.Millisecond
+	l := New(1, window)
+
+	l.Attempt("stale")
+	if !l.Attempt("blocked") || l.Attempt("blocked") {
+		t.Fatal("the second attempt of a key with a limit of 1 was allowed")
+	}
+
+	time.Sleep(window)
+
+	if !l.Attempt("blocked") {
+		t.Error("attempt after the window was refused")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.attempts["stale"]; ok {
+		t.Error("the entry of an unused key was not evicted")
+	}
+}
@@ -6,16 +6,38 @@ import (
 	"github.com/pateldivyesh1323/futflare/server/internal/middleware"
 )
 
-func NewRouter() *mux.Router {
+func NewRouter(h *handlers.Handler) *mux.Router {
 	r := mux.NewRouter()
 
 	r.Use(middleware.AuthenticationMiddleware())
 	r.HandleFunc("/api", handlers.HomeHandler).Methods("GET")
-	r.HandleFunc("/api/capsule", handlers.CreateCapsule).Methods("POST")
-	r.HandleFunc("/api/capsule", handlers.GetAllCapsules).Methods("GET")
-	r.HandleFunc("/api/capsule/{id}", handlers.GetCapsule).Methods("GET")
-	r.HandleFunc("/api/capsule/{id}", handlers.DeleteCapsule).Methods("Delete")
-	r.HandleFunc("/api/uploader/presigned-url", handlers.GeneratePresignedURL).Methods("POST")
+	r.HandleFunc("/api/capsule", h.CreateCapsule).Methods("POST")
+	r.HandleFunc("/api/capsule", h.GetAllCapsules).Methods("GET")
+	r.HandleFunc("/api/capsule/{id}", h.GetCapsule).Methods("GET")
+	r.HandleFunc("/api/capsule/{id}", h.UpdateCapsule).Methods("PUT", "PATCH")
+	r.HandleFunc("/api/capsule/{id}/seal", h.SealCapsule).Methods("POST")
+	r.HandleFunc("/api/capsule/{id}/contributions", h.AddContribution).Methods("POST")
+	r.HandleFunc("/api/capsule/{id}/unlock", h.UnlockCapsule).Methods("POST")
+	r.HandleFunc("/api/capsule/{id}/check-in", h.CheckIn).Methods("POST")
+	r.HandleFunc("/api/capsule/{id}/key", h.TurnKey).Methods("POST")
+	r.HandleFunc("/api/capsule/{id}/key", h.RevokeKey).Methods("DELETE")
+	r.HandleFunc("/api/capsule/{id}/predictions/{predictionId}/answer", h.AnswerPrediction).Methods("POST")
+	r.HandleFunc("/api/capsule/{id}/predictions/{predictionId}/outcome", h.MarkPredictionOutcome).Methods("POST")
+	r.HandleFunc("/api/capsule/{id}/goals/{goalId}/assessment", h.AssessGoal).Methods("PUT")
+	r.HandleFunc("/api/capsule/{id}/series", h.GetCapsuleSeries).Methods("GET")
+	r.HandleFunc("/api/capsule/{id}/archive", h.ArchiveCapsule).Methods("POST")
+	r.HandleFunc("/api/capsule/{id}", h.DeleteCapsule).Methods("Delete")
+	r.HandleFunc("/api/uploader/presigned-url", h.GeneratePresignedURL).Methods("POST")
+	r.HandleFunc("/api/uploader/multipart", h.StartMultipartUpload).Methods("POST")
+	r.HandleFunc("/api/uploader/multipart/{id}", h.GetMultipartUpload).Methods("GET")
+	r.HandleFunc("/api/uploader/multipart/{id}/parts", h.SignMultipartParts).Methods("POST")
+	r.HandleFunc("/api/uploader/multipart/{id}/complete", h.CompleteMultipartUpload).Methods("POST")
+	r.HandleFunc("/api/uploader/multipart/{id}", h.AbortMultipartUpload).Methods("DELETE")
+	r.HandleFunc("/api/tus", h.TusOptions).Methods("OPTIONS")
+	r.HandleFunc("/api/tus", h.CreateTusUpload).Methods("POST")
+	r.HandleFunc("/api/tus/{id}", h.HeadTusUpload).Methods("HEAD")
+	r.HandleFunc("/api/tus/{id}", h.PatchTusUpload).Methods("PATCH")
+	r.HandleFunc("/api/tus/{id}", h.DeleteTusUpload).Methods("DELETE")
 
 	return r
 }
@@ -5,11 +5,13 @@ import (
 	"log"
 	"time"
 
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/pateldivyesh1323/futflare/server/internal/model"
+	"github.com/pateldivyesh1323/futflare/server/internal/store"
 )
 
-func UpdateCapsuleOpenStatus(ctx context.Context, capsuleCollection *mongo.Collection) {
+func UpdateCapsuleOpenStatus(ctx context.Context, capsules store.CapsuleStore, reminder CheckInReminder) {
 	ticker := time.NewTicker(1 * time.Minute)
 	defer ticker.Stop()
 
@@ -18,42 +20,109 @@ func UpdateCapsuleOpenStatus(ctx context.Context, capsuleCollection *mongo.Colle
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			updateCapsules(ctx, capsuleCollection)
+			sendCheckInReminders(ctx, capsules, reminder)
+			updateCapsules(ctx, capsules)
 		}
 	}
 }
 
-func updateCapsules(ctx context.Context, capsuleCollection *mongo.Collection) {
+func updateCapsules(ctx context.Context, capsules store.CapsuleStore) {
 	now := time.Now().UTC()
 
-	filter := bson.M{
-		"is_opened":           false,
-		"scheduled_open_date": bson.M{"$lte": now},
+	opened, err := capsules.OpenDue(ctx, now)
+	if err != nil {
+		log.Printf("Error updating capsules: %v", err)
+		return
 	}
 
-	update := bson.M{
-		"$set": bson.M{"is_opened": true},
+	if opened > 0 {
+		log.Printf("Opened %d capsules", opened)
+	} else {
+		log.Println("No capsules were updated")
 	}
 
-	result, err := capsuleCollection.UpdateMany(ctx, filter, update)
+	spawnRecurrences(ctx, capsules, now)
+
+	revealed, err := capsules.AdvanceReveals(ctx, now)
 	if err != nil {
-		log.Printf("Error updating capsules: %v", err)
+		log.Printf("Error advancing item reveals: %v", err)
 		return
 	}
 
-	if result.ModifiedCount > 0 {
-		log.Printf("Opened %d capsules", result.ModifiedCount)
-	} else {
-		log.Println("No capsules were updated")
+	if revealed > 0 {
+		log.Printf("Revealed items in %d capsules", revealed)
 	}
 }
 
-func CreateIndexes(ctx context.Context, capsuleCollection *mongo.Collection) error {
-	_, err := capsuleCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
-		Keys: bson.D{
-			{Key: "is_opened", Value: 1},
-			{Key: "scheduled_open_date", Value: 1},
-		},
-	})
-	return err
+// spawnRecurrences creates the next draft instance of every recurring capsule
+// that has opened. The instance keeps the title, description and participants
+// and is linked into the same series.
+func spawnRecurrences(ctx context.Context, capsules store.CapsuleStore, now time.Time) {
+	pending, err := capsules.ListPendingRecurrences(ctx)
+	if err != nil {
+		log.Printf("Error listing recurring capsules: %v", err)
+		return
+	}
+
+	for _, c := range pending {
+		var next *model.Capsule
+
+		openDate, ok := c.Recurrence.Next(c.ScheduledOpenDate)
+		for ok && !openDate.After(now) {
+			openDate, ok = c.Recurrence.Next(openDate)
+		}
+
+		if ok {
+			seriesID := c.ID
+			if c.SeriesID != nil {
+				seriesID = *c.SeriesID
+			}
+			recurrence := *c.Recurrence
+
+			next = &model.Capsule{
+				ID:                primitive.NewObjectID(),
+				Title:             c.Title,
+				Description:       c.Description,
+				Creator:           c.Creator,
+				Status:            model.CapsuleStatusDraft,
+				ParticipantEmails: c.ParticipantEmails,
+				ScheduledOpenDate: openDate,
+				Recurrence:        &recurrence,
+				SeriesID:          &seriesID,
+				CreatedAt:         now,
+			}
+		}
+
+		err := capsules.LinkNextInstance(ctx, c.ID, next)
+		if err == store.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			log.Printf("Error spawning next instance of capsule %s: %v", c.ID.Hex(), err)
+			continue
+		}
+
+		if next != nil {
+			log.Printf("Spawned capsule %s from recurring capsule %s", next.ID.Hex(), c.ID.Hex())
+		}
+	}
+}
+
+func sendCheckInReminders(ctx context.Context, capsules store.CapsuleStore, reminder CheckInReminder) {
+	due, err := capsules.ListDueReminders(ctx, time.Now().UTC())
+	if err != nil {
+		log.Printf("Error listing check-in reminders: %v", err)
+		return
+	}
+
+	for _, c := range due {
+		if err := reminder.RemindCheckIn(ctx, c); err != nil {
+			log.Printf("Error sending check-in reminder for capsule %s: %v", c.ID.Hex(), err)
+			continue
+		}
+
+		if err := capsules.MarkReminded(ctx, c.ID, c.DeadMansSwitch.RemindAt); err != nil {
+			log.Printf("Error marking check-in reminder for capsule %s: %v", c.ID.Hex(), err)
+		}
+	}
 }
@@ -0,0 +1,123 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/pateldivyesh1323/futflare/server/internal/model"
+	"github.com/pateldivyesh1323/futflare/server/internal/store"
+)
+
+func TestSpawnRecurrences(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	capsules := store.NewMemoryCapsuleStore()
+
+	// The capsule opened two weeks late, the missed occurrences are skipped.
+	weekly := model.Capsule{
+		ID:                primitive.NewObjectID(),
+		Title:             "Weekly",
+		Creator:           "creator",
+		Status:            model.CapsuleStatusOpened,
+		ParticipantEmails: []string{"p@example.com"},
+		ScheduledOpenDate: now.Add(-15 * 24 * time.Hour),
+		Recurrence:        &model.Recurrence{Frequency: model.RecurrenceWeekly},
+	}
+	until := now.Add(-time.Hour)
+	ended := model.Capsule{
+		ID:                primitive.NewObjectID(),
+		Title:             "Ended",
+		Creator:           "creator",
+		Status:            model.CapsuleStatusOpened,
+		ScheduledOpenDate: now.Add(-2 * time.Hour),
+		Recurrence:        &model.Recurrence{Frequency: model.RecurrenceYearly, Until: &until},
+	}
+	for _, c := range []model.Capsule{weekly, ended} {
+		if err := capsules.Create(ctx, &c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	spawnRecurrences(ctx, capsules, now)
+	spawnRecurrences(ctx, capsules, now)
+
+	series, err := capsules.ListSeries(ctx, weekly.ID, "creator", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(series) != 1 {
+		t.Fatalf("series has %d instances, want 1", len(series))
+	}
+
+	next := series[0]
+	if want := weekly.ScheduledOpenDate.Add(21 * 24 * time.Hour); !next.ScheduledOpenDate.Equal(want) {
+		t.Errorf("next instance opens %v, want %v", next.ScheduledOpenDate, want)
+	}
+	if next.Status != model.CapsuleStatusDraft || next.Title != "Weekly" || len(next.ParticipantEmails) != 1 {
+		t.Errorf("next instance = %+v, want a draft copy of the capsule", next)
+	}
+
+	pending, err := capsules.ListPendingRecurrences(ctx)
+	if err != nil || len(pending) != 0 {
+		t.Errorf("ListPendingRecurrences = %d capsules, %v, want none", len(pending), err)
+	}
+}
+
+type recordingReminder struct {
+	reminded []primitive.ObjectID
+}
+
+func (r *recordingReminder) RemindCheckIn(ctx context.Context, c model.Capsule) error {
+	r.reminded = append(r.reminded, c.ID)
+	return nil
+}
+
+func TestSendCheckInReminders(t *testing.T) {
+	ctx := context.Background()
+	capsules := store.NewMemoryCapsuleStore()
+
+	newSwitchCapsule := func(lastCheckIn time.Time) model.Capsule {
+		sw := &model.DeadMansSwitch{CheckInDays: 7, GraceDays: 1}
+		sw.CheckIn(lastCheckIn, 48*time.Hour)
+		c := model.Capsule{
+			ID:             primitive.NewObjectID(),
+			Creator:        "creator",
+			Status:         model.CapsuleStatusSealed,
+			DeadMansSwitch: sw,
+		}
+		if err := capsules.Create(ctx, &c); err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	due := newSwitchCapsule(time.Now().AddDate(0, 0, -6))
+	newSwitchCapsule(time.Now())
+
+	reminder := &recordingReminder{}
+	sendCheckInReminders(ctx, capsules, reminder)
+	sendCheckInReminders(ctx, capsules, reminder)
+
+	if len(reminder.reminded) != 1 || reminder.reminded[0] != due.ID {
+		t.Fatalf("reminded %v, want only %s once", reminder.reminded, due.ID.Hex())
+	}
+
+	// Missing the deadline and the grace period opens the capsule.
+	sw := *due.DeadMansSwitch
+	sw.CheckIn(time.Now().AddDate(0, 0, -9), 48*time.Hour)
+	if err := capsules.SetDeadMansSwitch(ctx, due.ID, &sw); err != nil {
+		t.Fatal(err)
+	}
+	updateCapsules(ctx, capsules)
+
+	got, err := capsules.GetForUser(ctx, due.ID, "creator", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != model.CapsuleStatusOpened {
+		t.Errorf("status = %s, want %s", got.Status, model.CapsuleStatusOpened)
+	}
+}
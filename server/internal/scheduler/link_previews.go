@@ -0,0 +1,104 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/pateldivyesh1323/futflare/server/internal/model"
+	"github.com/pateldivyesh1323/futflare/server/internal/preview"
+	"github.com/pateldivyesh1323/futflare/server/internal/store"
+)
+
+const linkPreviewQueueSize = 1000
+
+// LinkPreviewQueue captures the previews of the links of sealed capsules in
+// the background, so sealing a capsule does not wait for slow sites.
+type LinkPreviewQueue struct {
+	jobs chan linkPreviewJob
+}
+
+type linkPreviewJob struct {
+	capsuleID primitive.ObjectID
+	urls      []string
+}
+
+func NewLinkPreviewQueue() *LinkPreviewQueue {
+	return &LinkPreviewQueue{jobs: make(chan linkPreviewJob, linkPreviewQueueSize)}
+}
+
+// Enqueue schedules the links among the items of a capsule that have no
+// preview yet. It never blocks, links that do not fit into the queue stay
+// without a preview.
+func (q *LinkPreviewQueue) Enqueue(capsuleID primitive.ObjectID, items []model.ContentItem) {
+	var urls []string
+	for _, item := range items {
+		if link, ok := item.Content.(*model.LinkContent); ok && link.Preview == nil {
+			urls = append(urls, link.URL)
+		}
+	}
+	if len(urls) == 0 {
+		return
+	}
+
+	select {
+	case q.jobs <- linkPreviewJob{capsuleID: capsuleID, urls: urls}:
+	default:
+		log.Printf("Link preview queue is full, leaving the links of capsule %s without a preview", capsuleID.Hex())
+	}
+}
+
+// Run captures enqueued previews until ctx is cancelled.
+func (q *LinkPreviewQueue) Run(ctx context.Context, capsules store.CapsuleStore, previews preview.Fetcher) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-q.jobs:
+			if err := CaptureLinkPreviews(ctx, capsules, previews, job.capsuleID, job.urls); err != nil {
+				log.Printf("Error storing link previews of capsule %s: %v", job.capsuleID.Hex(), err)
+			}
+		}
+	}
+}
+
+// CaptureLinkPreviews fetches the pages at urls and stores their previews
+// on the links of the capsule. A link whose page cannot be fetched is kept
+// without a preview.
+func CaptureLinkPreviews(ctx context.Context, capsules store.CapsuleStore, previews preview.Fetcher, capsuleID primitive.ObjectID, urls []string) error {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	captured := map[string]*model.LinkPreview{}
+
+	for _, url := range urls {
+		wg.Add(1)
+		go func(url string) {
+			defer wg.Done()
+
+			p, err := previews.Fetch(ctx, url)
+			if err != nil {
+				log.Printf("Failed to capture preview of %s: %v", url, err)
+				return
+			}
+
+			mu.Lock()
+			captured[url] = p
+			mu.Unlock()
+		}(url)
+	}
+
+	wg.Wait()
+
+	if len(captured) == 0 {
+		return nil
+	}
+
+	err := capsules.SetLinkPreviews(ctx, capsuleID, captured)
+	if err == store.ErrNotFound {
+		// The capsule was deleted meanwhile.
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,63 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/pateldivyesh1323/futflare/server/internal/model"
+	"github.com/pateldivyesh1323/futflare/server/internal/preview"
+	"github.com/pateldivyesh1323/futflare/server/internal/store"
+)
+
+func TestLinkPreviewQueue(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	capsules := store.NewMemoryCapsuleStore()
+	c := &model.Capsule{
+		ID:      primitive.NewObjectID(),
+		Creator: "creator",
+		Status:  model.CapsuleStatusSealed,
+		ContentItems: []model.ContentItem{
+			{Type: model.ContentTypeLink, Content: &model.LinkContent{URL: "https://example.com/a"}},
+			{Type: model.ContentTypeLink, Content: &model.LinkContent{URL: "https://example.com/gone"}},
+		},
+	}
+	if err := capsules.Create(ctx, c); err != nil {
+		t.Fatal(err)
+	}
+
+	previews := preview.StubFetcher{Previews: map[string]model.LinkPreview{
+		"https://example.com/a": {Title: "A"},
+	}}
+	q := NewLinkPreviewQueue()
+	go q.Run(ctx, capsules, previews)
+	q.Enqueue(c.ID, c.ContentItems)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got, err := capsules.GetForUser(ctx, c.ID, "creator", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		first := got.ContentItems[0].Content.(*model.LinkContent)
+		if first.Preview != nil {
+			if first.Preview.Title != "A" {
+				t.Errorf("preview title = %q, want A", first.Preview.Title)
+			}
+			if gone := got.ContentItems[1].Content.(*model.LinkContent); gone.Preview != nil {
+				t.Errorf("link that failed to fetch has preview %+v", gone.Preview)
+			}
+			return
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("the preview was not captured")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
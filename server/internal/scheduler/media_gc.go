@@ -0,0 +1,190 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/pateldivyesh1323/futflare/server/internal/model"
+	"github.com/pateldivyesh1323/futflare/server/internal/storage"
+	"github.com/pateldivyesh1323/futflare/server/internal/store"
+)
+
+const mediaDeletionQueueSize = 1000
+
+type MediaGCOptions struct {
+	// GracePeriod protects objects that were uploaded recently but whose
+	// capsule has not been saved yet.
+	GracePeriod time.Duration
+	// PendingGracePeriod protects the pending data of uploads in progress.
+	// It should be at least the lifetime of an upload session, older pending
+	// data was left behind by a failed or aborted upload.
+	PendingGracePeriod time.Duration
+	// DryRun only reports the orphaned objects without deleting them.
+	DryRun bool
+}
+
+// MediaGCReport describes one run of the media garbage collector.
+type MediaGCReport struct {
+	Scanned    int
+	Referenced int
+	// Recent counts unreferenced objects still inside the grace period.
+	Recent int
+	// Pending counts the objects of uploads that may still be in progress.
+	Pending  int
+	Orphaned []storage.Object
+	Deleted  int
+	Failed   int
+	DryRun   bool
+}
+
+// RunMediaGC collects orphaned media once at startup and then every interval
+// until ctx is cancelled, so servers restarted more often than the interval
+// still collect.
+func RunMediaGC(ctx context.Context, capsules store.CapsuleStore, files storage.ObjectStore, interval time.Duration, opts MediaGCOptions) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		report, err := CollectOrphanedMedia(ctx, capsules, files, opts)
+		if err != nil {
+			log.Printf("Error collecting orphaned media: %v", err)
+		} else {
+			report.Log()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CollectOrphanedMedia deletes the objects of the object store that no
+// capsule refers to and that are older than the grace period.
+func CollectOrphanedMedia(ctx context.Context, capsules store.CapsuleStore, files storage.ObjectStore, opts MediaGCOptions) (*MediaGCReport, error) {
+	// Objects are listed before the references are loaded, so an object
+	// uploaded and saved in between is never seen as orphaned.
+	entries, err := files.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	uploads, err := capsules.ListUploads(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	referenced := map[string]bool{}
+	for _, upload := range uploads {
+		if upload.ObjectKey != "" {
+			referenced[upload.ObjectKey] = true
+		}
+		if key, ok := storage.KeyFromLegacyURL(upload.URL); ok {
+			referenced[key] = true
+		}
+	}
+
+	report := &MediaGCReport{Scanned: len(entries), DryRun: opts.DryRun}
+	cutoff := time.Now().Add(-opts.GracePeriod)
+	pendingCutoff := time.Now().Add(-max(opts.GracePeriod, opts.PendingGracePeriod))
+	for _, entry := range entries {
+		switch {
+		case referenced[entry.Key]:
+			report.Referenced++
+		case strings.HasPrefix(entry.Key, storage.PendingPrefix):
+			if entry.LastModified.After(pendingCutoff) {
+				report.Pending++
+			} else {
+				report.Orphaned = append(report.Orphaned, entry)
+			}
+		case entry.LastModified.After(cutoff):
+			report.Recent++
+		default:
+			report.Orphaned = append(report.Orphaned, entry)
+		}
+	}
+
+	if opts.DryRun {
+		return report, nil
+	}
+
+	for _, entry := range report.Orphaned {
+		if err := deleteUnreferenced(ctx, capsules, files, entry.Key); err != nil {
+			log.Printf("Error deleting orphaned media %s: %v", entry.Key, err)
+			report.Failed++
+			continue
+		}
+		report.Deleted++
+	}
+
+	return report, nil
+}
+
+func (r *MediaGCReport) Log() {
+	var size int64
+	for _, entry := range r.Orphaned {
+		size += entry.Size
+	}
+
+	if r.DryRun {
+		for _, entry := range r.Orphaned {
+			log.Printf("Orphaned media %s (%d bytes, last modified %s)", entry.Key, entry.Size, entry.LastModified.Format(time.RFC3339))
+		}
+		log.Printf("Media GC dry run: scanned %d objects, %d referenced, %d within grace period, %d pending, %d orphaned (%d bytes)",
+			r.Scanned, r.Referenced, r.Recent, r.Pending, len(r.Orphaned), size)
+		return
+	}
+
+	log.Printf("Media GC: scanned %d objects, %d referenced, %d within grace period, %d pending, deleted %d orphaned (%d bytes), %d failed",
+		r.Scanned, r.Referenced, r.Recent, r.Pending, r.Deleted, size, r.Failed)
+}
+
+// MediaDeletionQueue deletes the media of deleted capsules in the
+// background, so deleting a capsule does not wait for the object store.
+type MediaDeletionQueue struct {
+	keys chan string
+}
+
+func NewMediaDeletionQueue() *MediaDeletionQueue {
+	return &MediaDeletionQueue{keys: make(chan string, mediaDeletionQueueSize)}
+}
+
+// Enqueue schedules objects for deletion. It never blocks, keys that do not
+// fit into the queue are left for the garbage collector.
+func (q *MediaDeletionQueue) Enqueue(keys ...string) {
+	for _, key := range keys {
+		select {
+		case q.keys <- key:
+		default:
+			log.Printf("Media deletion queue is full, leaving %s to the garbage collector", key)
+		}
+	}
+}
+
+// Run deletes enqueued objects until ctx is cancelled.
+func (q *MediaDeletionQueue) Run(ctx context.Context, capsules store.CapsuleStore, files storage.ObjectStore) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case key := <-q.keys:
+			if err := deleteUnreferenced(ctx, capsules, files, key); err != nil {
+				log.Printf("Error deleting media %s: %v", key, err)
+			}
+		}
+	}
+}
+
+// deleteUnreferenced deletes an object unless a capsule has started to refer
+// to it, e.g. because it was copied into another capsule.
+func deleteUnreferenced(ctx context.Context, capsules store.CapsuleStore, files storage.ObjectStore, key string) error {
+	used, err := capsules.UploadReferenced(ctx, model.Upload{ObjectKey: key, URL: storage.LegacyURL(key)})
+	if err != nil || used {
+		return err
+	}
+
+	return files.Delete(ctx, key)
+}
@@ -0,0 +1,128 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/pateldivyesh1323/futflare/server/internal/model"
+	"github.com/pateldivyesh1323/futflare/server/internal/storage"
+	"github.com/pateldivyesh1323/futflare/server/internal/store"
+)
+
+// putObject stores an object last modified age ago in the local store
+// rooted at dir.
+func putObject(t *testing.T, files storage.ObjectStore, dir, key string, age time.Duration) {
+	t.Helper()
+
+	body := []byte("data")
+	if err := files.Put(context.Background(), key, bytes.NewReader(body), int64(len(body))); err != nil {
+		t.Fatal(err)
+	}
+
+	modified := time.Now().Add(-age)
+	if err := os.Chtimes(filepath.Join(dir, "objects", filepath.FromSlash(key)), modified, modified); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCollectOrphanedMedia(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	files, err := storage.NewLocalStore(dir, "http://localhost", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	capsules := store.NewMemoryCapsuleStore()
+	err = capsules.Create(ctx, &model.Capsule{
+		Creator: "creator",
+		Status:  model.CapsuleStatusSealed,
+		ContentItems: []model.ContentItem{
+			{Type: model.ContentTypeImage, Content: &model.ImageContent{Upload: model.Upload{ObjectKey: "uploads/u/kept.png"}}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	putObject(t, files, dir, "uploads/u/kept.png", 72*time.Hour)
+	putObject(t, files, dir, "uploads/u/orphan.png", 72*time.Hour)
+	putObject(t, files, dir, "uploads/u/recent.png", time.Hour)
+	putObject(t, files, dir, storage.PendingKey("live/1-0"), 3*time.Hour)
+	putObject(t, files, dir, storage.PendingKey("stale/1-0"), 72*time.Hour)
+
+	opts := MediaGCOptions{GracePeriod: 2 * time.Hour, PendingGracePeriod: 24 * time.Hour}
+
+	opts.DryRun = true
+	report, err := CollectOrphanedMedia(ctx, capsules, files, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Referenced != 1 || report.Recent != 1 || report.Pending != 1 || len(report.Orphaned) != 2 || report.Deleted != 0 {
+		t.Errorf("dry run report = %+v", report)
+	}
+
+	opts.DryRun = false
+	report, err = CollectOrphanedMedia(ctx, capsules, files, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Deleted != 2 || report.Failed != 0 {
+		t.Errorf("report = %+v, want 2 deleted", report)
+	}
+
+	objects, err := files.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, object := range objects {
+		keys = append(keys, object.Key)
+	}
+	sort.Strings(keys)
+
+	want := []string{storage.PendingKey("live/1-0"), "uploads/u/kept.png", "uploads/u/recent.png"}
+	if len(keys) != len(want) {
+		t.Fatalf("remaining objects = %v, want %v", keys, want)
+	}
+	for i := range want {
+		if keys[i] != want[i] {
+			t.Errorf("remaining objects = %v, want %v", keys, want)
+			break
+		}
+	}
+}
+
+func TestDeleteUnreferencedKeepsReusedObjects(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	files, err := storage.NewLocalStore(dir, "http://localhost", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	capsules := store.NewMemoryCapsuleStore()
+	err = capsules.Create(ctx, &model.Capsule{
+		Creator: "creator",
+		Status:  model.CapsuleStatusSealed,
+		ContentItems: []model.ContentItem{
+			{Type: model.ContentTypeImage, Content: &model.ImageContent{Upload: model.Upload{ObjectKey: "uploads/u/used.png"}}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	putObject(t, files, dir, "uploads/u/used.png", 0)
+
+	if err := deleteUnreferenced(ctx, capsules, files, "uploads/u/used.png"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := files.Head(ctx, "uploads/u/used.png"); err != nil {
+		t.Errorf("referenced object was deleted: %v", err)
+	}
+}
@@ -0,0 +1,23 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+
+	"github.com/pateldivyesh1323/futflare/server/internal/model"
+)
+
+// CheckInReminder is called when the creator of a dead man's switch capsule
+// is close to missing a check-in.
+type CheckInReminder interface {
+	RemindCheckIn(ctx context.Context, c model.Capsule) error
+}
+
+// LogReminder only logs reminders. It is used until an email or push
+// notification sender is wired in.
+type LogReminder struct{}
+
+func (LogReminder) RemindCheckIn(ctx context.Context, c model.Capsule) error {
+	log.Printf("Creator %s has to check in to capsule %s before %s", c.Creator, c.ID.Hex(), c.DeadMansSwitch.Deadline())
+	return nil
+}
@@ -0,0 +1,59 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/pateldivyesh1323/futflare/server/internal/model"
+	"github.com/pateldivyesh1323/futflare/server/internal/storage"
+	"github.com/pateldivyesh1323/futflare/server/internal/store"
+)
+
+// AbortExpiredUploads aborts multipart uploads that were not completed
+// before their session expired, every interval until ctx is cancelled. The
+// object store keeps the parts of an upload until it is aborted.
+func AbortExpiredUploads(ctx context.Context, sessions store.UploadSessionStore, files storage.ObjectStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			abortExpiredUploads(ctx, sessions, files)
+		}
+	}
+}
+
+func abortExpiredUploads(ctx context.Context, sessions store.UploadSessionStore, files storage.ObjectStore) {
+	expired, err := sessions.ListExpiredUploadSessions(ctx, time.Now())
+	if err != nil {
+		log.Printf("Error listing expired uploads: %v", err)
+		return
+	}
+
+	for _, session := range expired {
+		err := files.AbortMultipart(ctx, session.ObjectKey, session.UploadID)
+		if err != nil && err != storage.ErrNotFound {
+			log.Printf("Error aborting expired upload %s: %v", session.ID.Hex(), err)
+			continue
+		}
+
+		if session.TailSize > 0 {
+			if err := files.Delete(ctx, storage.PendingKey(session.TailName())); err != nil {
+				log.Printf("Error deleting pending data of expired upload %s: %v", session.ID.Hex(), err)
+			}
+		}
+
+		err = sessions.UpdateUploadStatus(ctx, session.ID, model.UploadSessionUploading, model.UploadSessionAborted)
+		if err != nil && err != store.ErrNotFound {
+			log.Printf("Error marking expired upload %s as aborted: %v", session.ID.Hex(), err)
+		}
+	}
+
+	if len(expired) > 0 {
+		log.Printf("Aborted %d expired uploads", len(expired))
+	}
+}
@@ -0,0 +1,531 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pateldivyesh1323/futflare/server/internal/utils"
+)
+
+// LocalPathPrefix is the path under which LocalStore serves its signed URLs.
+const LocalPathPrefix = "/storage/"
+
+// LocalStore keeps objects on the local disk and serves its presigned URLs
+// itself, so the upload flow works without a cloud account. Mount it on
+// LocalPathPrefix outside of the authenticated API, the signature of a URL
+// is its authorization.
+type LocalStore struct {
+	objects   string
+	multipart string
+	tmp       string
+	baseURL   string
+	secret    []byte
+}
+
+// NewLocalStore stores objects below dir. Signed URLs start with baseURL,
+// the public address of the server, and are signed with secret.
+func NewLocalStore(dir, baseURL string, secret []byte) (*LocalStore, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &LocalStore{
+		objects:   filepath.Join(dir, "objects"),
+		multipart: filepath.Join(dir, "multipart"),
+		tmp:       filepath.Join(dir, "tmp"),
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
+		secret:    secret,
+	}
+
+	for _, d := range []string{s.objects, s.multipart, s.tmp} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+func (s *LocalStore) PresignPut(ctx context.Context, key, contentType string, size int64, expiry time.Duration) (string, error) {
+	return s.sign(signedRequest{method: http.MethodPut, key: key, contentType: contentType, size: size}, expiry)
+}
+
+func (s *LocalStore) PresignGet(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	return s.sign(signedRequest{method: http.MethodGet, key: key}, expiry)
+}
+
+func (s *LocalStore) Head(ctx context.Context, key string) (*ObjectInfo, error) {
+	name, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &ObjectInfo{
+		Size:        info.Size(),
+		ContentType: mime.TypeByExtension(path.Ext(key)),
+	}, nil
+}
+
+func (s *LocalStore) Put(ctx context.Context, key string, body io.ReadSeeker, size int64) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	return s.write(name, io.LimitReader(body, size), size)
+}
+
+func (s *LocalStore) ReadPrefix(ctx context.Context, key string, n int64) ([]byte, error) {
+	name, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return io.ReadAll(io.LimitReader(f, n))
+}
+
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	// Like S3, deleting a missing object is not an error.
+	if err := os.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+func (s *LocalStore) List(ctx context.Context) ([]Object, error) {
+	var objects []Object
+
+	err := filepath.WalkDir(s.objects, func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(s.objects, name)
+		if err != nil {
+			return err
+		}
+
+		objects = append(objects, Object{
+			Key:          filepath.ToSlash(rel),
+			Size:         info.Size(),
+			LastModified: info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return objects, nil
+}
+
+// CreateMultipart keeps the parts of an upload in a directory named after
+// the upload ID, next to a file holding the key of the object.
+func (s *LocalStore) CreateMultipart(ctx context.Context, key, contentType string) (string, error) {
+	if _, err := s.path(key); err != nil {
+		return "", err
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	uploadID := hex.EncodeToString(id)
+
+	dir := filepath.Join(s.multipart, uploadID)
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "key"), []byte(key), 0o644); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+
+	return uploadID, nil
+}
+
+func (s *LocalStore) PresignPart(ctx context.Context, key, uploadID string, number int32, size int64, expiry time.Duration) (string, error) {
+	return s.sign(signedRequest{method: http.MethodPut, key: key, size: size, uploadID: uploadID, part: number}, expiry)
+}
+
+func (s *LocalStore) PutPart(ctx context.Context, key, uploadID string, number int32, body io.ReadSeeker, size int64) (*Part, error) {
+	dir, err := s.uploadDir(key, uploadID)
+	if err != nil {
+		return nil, err
+	}
+
+	name := s.partPath(dir, number)
+	if err := s.write(name, io.LimitReader(body, size), size); err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Part{
+		Number: number,
+		ETag:   partETag(info),
+		Size:   info.Size(),
+	}, nil
+}
+
+func (s *LocalStore) ListParts(ctx context.Context, key, uploadID string) ([]Part, error) {
+	dir, err := s.uploadDir(key, uploadID)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(filepath.Join(dir, "parts"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return []Part{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	parts := make([]Part, 0, len(entries))
+	for _, entry := range entries {
+		number, err := strconv.ParseInt(entry.Name(), 10, 32)
+		if err != nil {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		parts = append(parts, Part{
+			Number: int32(number),
+			ETag:   partETag(info),
+			Size:   info.Size(),
+		})
+	}
+
+	sort.Slice(parts, func(i, j int) bool { return parts[i].Number < parts[j].Number })
+	return parts, nil
+}
+
+func (s *LocalStore) CompleteMultipart(ctx context.Context, key, uploadID string, parts []Part) error {
+	dir, err := s.uploadDir(key, uploadID)
+	if err != nil {
+		return err
+	}
+
+	name, _ := s.path(key)
+
+	readers := make([]io.Reader, 0, len(parts))
+	var size int64
+	for _, part := range parts {
+		f, err := os.Open(s.partPath(dir, part.Number))
+		if err != nil {
+			return fmt.Errorf("part %d: %w", part.Number, err)
+		}
+		defer f.Close()
+
+		info, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		if partETag(info) != part.ETag {
+			return fmt.Errorf("part %d was uploaded again", part.Number)
+		}
+
+		readers = append(readers, f)
+		size += info.Size()
+	}
+
+	if err := s.write(name, io.MultiReader(readers...), size); err != nil {
+		return err
+	}
+
+	return os.RemoveAll(dir)
+}
+
+func (s *LocalStore) AbortMultipart(ctx context.Context, key, uploadID string) error {
+	dir, err := s.uploadDir(key, uploadID)
+	if err != nil {
+		return err
+	}
+
+	return os.RemoveAll(dir)
+}
+
+// ServeHTTP downloads objects and uploads objects and parts through the URLs
+// signed by the store.
+func (s *LocalStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	req := signedRequest{
+		method:   r.Method,
+		key:      strings.TrimPrefix(r.URL.Path, LocalPathPrefix),
+		uploadID: query.Get("uploadId"),
+	}
+
+	name, err := s.path(req.key)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, "Invalid object key", nil)
+		return
+	}
+
+	switch req.method {
+	case http.MethodHead:
+		req.method = http.MethodGet
+	case http.MethodGet, http.MethodPut:
+	default:
+		utils.SendJSONResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	req.size, _ = strconv.ParseInt(query.Get("size"), 10, 64)
+	if part, err := strconv.ParseInt(query.Get("partNumber"), 10, 32); err == nil {
+		req.part = int32(part)
+	}
+	// Like S3, the content type is signed for whole objects only.
+	if req.method == http.MethodPut && req.uploadID == "" {
+		req.contentType = r.Header.Get("Content-Type")
+	}
+
+	if !s.verify(req, query.Get("expires"), query.Get("signature")) {
+		utils.SendJSONResponse(w, http.StatusForbidden, "Invalid or expired signature", nil)
+		return
+	}
+
+	switch req.method {
+	case http.MethodGet:
+		f, err := os.Open(name)
+		if err != nil {
+			utils.SendJSONResponse(w, http.StatusNotFound, "Object not found", nil)
+			return
+		}
+		defer f.Close()
+
+		info, err := f.Stat()
+		if err != nil {
+			utils.SendJSONResponse(w, http.StatusInternalServerError, "Internal server error", nil)
+			return
+		}
+
+		// Objects are served from the API origin, so only media is shown
+		// inline. Anything else, e.g. an HTML file item, is downloaded.
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		contentType := mime.TypeByExtension(path.Ext(req.key))
+		if !isInlineMedia(contentType) {
+			contentType = "application/octet-stream"
+			w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": path.Base(req.key)}))
+		}
+		w.Header().Set("Content-Type", contentType)
+
+		http.ServeContent(w, r, path.Base(req.key), info.ModTime(), f)
+
+	case http.MethodPut:
+		if r.ContentLength != req.size {
+			utils.SendJSONResponse(w, http.StatusBadRequest, "Content length does not match the signed size", nil)
+			return
+		}
+
+		if req.uploadID != "" {
+			dir, err := s.uploadDir(req.key, req.uploadID)
+			if err != nil {
+				utils.SendJSONResponse(w, http.StatusNotFound, "Upload not found", nil)
+				return
+			}
+			name = s.partPath(dir, req.part)
+		}
+
+		if err := s.write(name, io.LimitReader(r.Body, req.size), req.size); err != nil {
+			utils.SendJSONResponse(w, http.StatusBadRequest, "Failed to store object", nil)
+			return
+		}
+
+		if req.uploadID != "" {
+			if info, err := os.Stat(name); err == nil {
+				w.Header().Set("ETag", partETag(info))
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// isInlineMedia reports whether a browser can safely show content of the
+// media type in place. SVG images may contain scripts.
+func isInlineMedia(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch strings.SplitN(mediaType, "/", 2)[0] {
+	case "image":
+		return mediaType != "image/svg+xml"
+	case "video", "audio":
+		return true
+	}
+	return false
+}
+
+// write stores exactly size bytes of body under name. The file appears only
+// once it is complete.
+func (s *LocalStore) write(name string, body io.Reader, size int64) error {
+	tmp, err := os.CreateTemp(s.tmp, "upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	n, err := io.Copy(tmp, body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if n != size {
+		return io.ErrUnexpectedEOF
+	}
+
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), name)
+}
+
+// path returns the file of an object. Keys that could escape the store are
+// rejected.
+func (s *LocalStore) path(key string) (string, error) {
+	if !fs.ValidPath(key) || key == "." || strings.Contains(key, `\`) {
+		return "", errors.New("invalid object key")
+	}
+	return filepath.Join(s.objects, filepath.FromSlash(key)), nil
+}
+
+// uploadDir returns the directory of a multipart upload of key. It returns
+// ErrNotFound when there is no such upload.
+func (s *LocalStore) uploadDir(key, uploadID string) (string, error) {
+	if _, err := hex.DecodeString(uploadID); err != nil || uploadID == "" {
+		return "", ErrNotFound
+	}
+
+	dir := filepath.Join(s.multipart, uploadID)
+	stored, err := os.ReadFile(filepath.Join(dir, "key"))
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && string(stored) != key) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return dir, nil
+}
+
+func (s *LocalStore) partPath(dir string, number int32) string {
+	return filepath.Join(dir, "parts", strconv.Itoa(int(number)))
+}
+
+// partETag identifies the content of a part. A part uploaded again gets a
+// new ETag.
+func partETag(info fs.FileInfo) string {
+	return fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size())
+}
+
+// signedRequest holds the fields of a request that are covered by the
+// signature of a URL.
+type signedRequest struct {
+	method      string
+	key         string
+	contentType string
+	size        int64
+	uploadID    string
+	part        int32
+}
+
+func (s *LocalStore) sign(req signedRequest, expiry time.Duration) (string, error) {
+	if _, err := s.path(req.key); err != nil {
+		return "", err
+	}
+
+	expires := strconv.FormatInt(time.Now().Add(expiry).Unix(), 10)
+
+	query := url.Values{}
+	query.Set("expires", expires)
+	if req.method == http.MethodPut {
+		query.Set("size", strconv.FormatInt(req.size, 10))
+	}
+	if req.uploadID != "" {
+		query.Set("uploadId", req.uploadID)
+		query.Set("partNumber", strconv.Itoa(int(req.part)))
+	}
+	query.Set("signature", s.signature(req, expires))
+
+	u := url.URL{Path: LocalPathPrefix + req.key, RawQuery: query.Encode()}
+	return s.baseURL + u.String(), nil
+}
+
+func (s *LocalStore) verify(req signedRequest, expires, signature string) bool {
+	deadline, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > deadline {
+		return false
+	}
+
+	expected := s.signature(req, expires)
+	return hmac.Equal([]byte(signature), []byte(expected))
+}
+
+func (s *LocalStore) signature(req signedRequest, expires string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(strings.Join([]string{
+		req.method,
+		req.key,
+		req.contentType,
+		strconv.FormatInt(req.size, 10),
+		req.uploadID,
+		strconv.Itoa(int(req.part)),
+		expires,
+	}, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
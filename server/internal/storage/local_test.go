@@ -0,0 +1,212 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestLocalStore(t *testing.T) *LocalStore {
+	t.Helper()
+
+	s, err := NewLocalStore(t.TempDir(), "http://localhost:8080", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func putTestObject(t *testing.T, s *LocalStore, key string, body []byte) {
+	t.Helper()
+
+	if err := s.Put(context.Background(), key, bytes.NewReader(body), int64(len(body))); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// serve sends a request to the signed URL rawURL.
+func serve(t *testing.T, s *LocalStore, method, rawURL string, body []byte, contentType string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(method, u.RequestURI(), bytes.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	return w
+}
+
+// tamper returns rawURL with the query parameter key set to value.
+func tamper(t *testing.T, rawURL, key, value string) string {
+	t.Helper()
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := u.Query()
+	query.Set(key, value)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+func TestLocalStoreSignedGet(t *testing.T) {
+	ctx := context.Background()
+	s := newTestLocalStore(t)
+	putTestObject(t, s, "uploads/user/photo.png", []byte("photo"))
+	putTestObject(t, s, "uploads/other/photo.png", []byte("other"))
+
+	signed, err := s.PresignGet(ctx, "uploads/user/photo.png", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(signed, "http://localhost:8080"+LocalPathPrefix) {
+		t.Errorf("signed URL %s is not served by the store", signed)
+	}
+
+	w := serve(t, s, http.MethodGet, signed, nil, "")
+	if w.Code != http.StatusOK || w.Body.String() != "photo" {
+		t.Fatalf("GET = %d %q, want the object", w.Code, w.Body.String())
+	}
+	if w := serve(t, s, http.MethodHead, signed, nil, ""); w.Code != http.StatusOK {
+		t.Errorf("HEAD = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	expired, err := s.PresignGet(ctx, "uploads/user/photo.png", -time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Hour).Unix()
+
+	tests := []struct {
+		name   string
+		method string
+		url    string
+	}{
+		{"expired", http.MethodGet, expired},
+		{"extended expiry", http.MethodGet, tamper(t, expired, "expires", strconv.FormatInt(deadline, 10))},
+		{"other key", http.MethodGet, strings.Replace(signed, "uploads/user/", "uploads/other/", 1)},
+		{"forged signature", http.MethodGet, tamper(t, signed, "signature", strings.Repeat("0", 64))},
+		{"missing signature", http.MethodGet, tamper(t, signed, "signature", "")},
+		{"upload through a download URL", http.MethodPut, signed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := serve(t, s, tt.method, tt.url, nil, ""); w.Code != http.StatusForbidden {
+				t.Errorf("%s = %d, want %d", tt.method, w.Code, http.StatusForbidden)
+			}
+		})
+	}
+}
+
+func TestLocalStoreSignedPut(t *testing.T) {
+	ctx := context.Background()
+	s := newTestLocalStore(t)
+	body := []byte("\x89PNG\r\n\x1a\n")
+
+	signed, err := s.PresignPut(ctx, "uploads/user/photo.png", "image/png", int64(len(body)), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		url         string
+		body        []byte
+		contentType string
+		want        int
+	}{
+		{"other content type", signed, body, "text/html", http.StatusForbidden},
+		{"larger signed size", tamper(t, signed, "size", "1000000"), body, "image/png", http.StatusForbidden},
+		{"shorter body", signed, body[:4], "image/png", http.StatusBadRequest},
+		{"signed request", signed, body, "image/png", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := serve(t, s, http.MethodPut, tt.url, tt.body, tt.contentType); w.Code != tt.want {
+				t.Errorf("PUT = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+
+	info, err := s.Head(ctx, "uploads/user/photo.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size != int64(len(body)) {
+		t.Errorf("stored object has %d bytes, want %d", info.Size, len(body))
+	}
+}
+
+func TestLocalStoreServesOnlyMediaInline(t *testing.T) {
+	ctx := context.Background()
+	s := newTestLocalStore(t)
+
+	tests := []struct {
+		key         string
+		contentType string
+		attachment  bool
+	}{
+		{"uploads/user/photo.png", "image/png", false},
+		{"uploads/user/clip.mp4", "video/mp4", false},
+		{"uploads/user/page.html", "application/octet-stream", true},
+		{"uploads/user/drawing.svg", "application/octet-stream", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			putTestObject(t, s, tt.key, []byte("<html><script>alert(1)</script></html>"))
+			signed, err := s.PresignGet(ctx, tt.key, time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			w := serve(t, s, http.MethodGet, signed, nil, "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("GET = %d, want %d", w.Code, http.StatusOK)
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("Content-Type = %s, want %s", got, tt.contentType)
+			}
+			if got := w.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+				t.Errorf("X-Content-Type-Options = %q, want nosniff", got)
+			}
+			if got := w.Header().Get("Content-Disposition"); strings.HasPrefix(got, "attachment") != tt.attachment {
+				t.Errorf("Content-Disposition = %q, want attachment %v", got, tt.attachment)
+			}
+		})
+	}
+}
+
+func TestLocalStoreRejectsEscapingKeys(t *testing.T) {
+	ctx := context.Background()
+	s := newTestLocalStore(t)
+
+	for _, key := range []string{"../secret", "uploads/../../secret", "/etc/passwd", `uploads\..\secret`, "."} {
+		if _, err := s.PresignGet(ctx, key, time.Minute); err == nil {
+			t.Errorf("PresignGet(%q) signed a URL", key)
+		}
+		if err := s.Put(ctx, key, bytes.NewReader([]byte("x")), 1); err == nil {
+			t.Errorf("Put(%q) stored an object", key)
+		}
+	}
+
+	w := serve(t, s, http.MethodGet, LocalPathPrefix+"..%2Fsecret?expires=0&signature=x", nil, "")
+	if w.Code != http.StatusBadRequest && w.Code != http.StatusForbidden {
+		t.Errorf("GET of an escaping key = %d, want it refused", w.Code)
+	}
+}
@@ -0,0 +1,241 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// S3Store keeps objects in an S3 bucket, or in a bucket of an S3 compatible
+// server such as MinIO.
+type S3Store struct {
+	client *s3.Client
+	bucket string
+}
+
+func NewS3Store(client *s3.Client, bucket string) *S3Store {
+	return &S3Store{
+		client: client,
+		bucket: bucket,
+	}
+}
+
+func (s *S3Store) PresignPut(ctx context.Context, key, contentType string, size int64, expiry time.Duration) (string, error) {
+	req, err := s3.NewPresignClient(s.client).PresignPutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(s.bucket),
+		Key:           aws.String(key),
+		ContentType:   aws.String(contentType),
+		ContentLength: aws.Int64(size),
+	}, s3.WithPresignExpires(expiry))
+	if err != nil {
+		return "", err
+	}
+
+	return req.URL, nil
+}
+
+func (s *S3Store) PresignGet(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	req, err := s3.NewPresignClient(s.client).PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(expiry))
+	if err != nil {
+		return "", err
+	}
+
+	return req.URL, nil
+}
+
+func (s *S3Store) Head(ctx context.Context, key string) (*ObjectInfo, error) {
+	out, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	return &ObjectInfo{
+		Size:        aws.ToInt64(out.ContentLength),
+		ContentType: aws.ToString(out.ContentType),
+	}, nil
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, body io.ReadSeeker, size int64) error {
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(s.bucket),
+		Key:           aws.String(key),
+		Body:          body,
+		ContentLength: aws.Int64(size),
+	})
+
+	return err
+}
+
+func (s *S3Store) ReadPrefix(ctx context.Context, key string, n int64) ([]byte, error) {
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		Range:  aws.String(fmt.Sprintf("bytes=0-%d", n-1)),
+	})
+	if err != nil {
+		return nil, notFound(err)
+	}
+	defer out.Body.Close()
+
+	return io.ReadAll(io.LimitReader(out.Body, n))
+}
+
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+
+	return err
+}
+
+func (s *S3Store) List(ctx context.Context) ([]Object, error) {
+	var objects []Object
+
+	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, object := range page.Contents {
+			objects = append(objects, Object{
+				Key:          aws.ToString(object.Key),
+				Size:         aws.ToInt64(object.Size),
+				LastModified: aws.ToTime(object.LastModified),
+			})
+		}
+	}
+
+	return objects, nil
+}
+
+func (s *S3Store) CreateMultipart(ctx context.Context, key, contentType string) (string, error) {
+	out, err := s.client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return aws.ToString(out.UploadId), nil
+}
+
+func (s *S3Store) PresignPart(ctx context.Context, key, uploadID string, number int32, size int64, expiry time.Duration) (string, error) {
+	req, err := s3.NewPresignClient(s.client).PresignUploadPart(ctx, &s3.UploadPartInput{
+		Bucket:        aws.String(s.bucket),
+		Key:           aws.String(key),
+		UploadId:      aws.String(uploadID),
+		PartNumber:    aws.Int32(number),
+		ContentLength: aws.Int64(size),
+	}, s3.WithPresignExpires(expiry))
+	if err != nil {
+		return "", err
+	}
+
+	return req.URL, nil
+}
+
+func (s *S3Store) PutPart(ctx context.Context, key, uploadID string, number int32, body io.ReadSeeker, size int64) (*Part, error) {
+	out, err := s.client.UploadPart(ctx, &s3.UploadPartInput{
+		Bucket:        aws.String(s.bucket),
+		Key:           aws.String(key),
+		UploadId:      aws.String(uploadID),
+		PartNumber:    aws.Int32(number),
+		Body:          body,
+		ContentLength: aws.Int64(size),
+	})
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	return &Part{
+		Number: number,
+		ETag:   aws.ToString(out.ETag),
+		Size:   size,
+	}, nil
+}
+
+func (s *S3Store) ListParts(ctx context.Context, key, uploadID string) ([]Part, error) {
+	var parts []Part
+
+	paginator := s3.NewListPartsPaginator(s.client, &s3.ListPartsInput{
+		Bucket:   aws.String(s.bucket),
+		Key:      aws.String(key),
+		UploadId: aws.String(uploadID),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, notFound(err)
+		}
+
+		for _, part := range page.Parts {
+			parts = append(parts, Part{
+				Number: aws.ToInt32(part.PartNumber),
+				ETag:   aws.ToString(part.ETag),
+				Size:   aws.ToInt64(part.Size),
+			})
+		}
+	}
+
+	return parts, nil
+}
+
+func (s *S3Store) CompleteMultipart(ctx context.Context, key, uploadID string, parts []Part) error {
+	completed := make([]types.CompletedPart, 0, len(parts))
+	for _, part := range parts {
+		completed = append(completed, types.CompletedPart{
+			ETag:       aws.String(part.ETag),
+			PartNumber: aws.Int32(part.Number),
+		})
+	}
+
+	_, err := s.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(s.bucket),
+		Key:             aws.String(key),
+		UploadId:        aws.String(uploadID),
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: completed},
+	})
+
+	return notFound(err)
+}
+
+func (s *S3Store) AbortMultipart(ctx context.Context, key, uploadID string) error {
+	_, err := s.client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(s.bucket),
+		Key:      aws.String(key),
+		UploadId: aws.String(uploadID),
+	})
+
+	return notFound(err)
+}
+
+func notFound(err error) error {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "NotFound", "NoSuchKey", "NoSuchUpload":
+			return ErrNotFound
+		}
+	}
+	return err
+}
@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pateldivyesh1323/futflare/server/internal/awslib"
+	"github.com/pateldivyesh1323/futflare/server/internal/config"
+)
+
+const (
+	BackendS3    = "s3"
+	BackendLocal = "local"
+
+	// PendingPrefix holds the data of uploads that are still in progress.
+	// Those objects are owned by their upload, not by a capsule.
+	PendingPrefix = "pending/"
+)
+
+var ErrNotFound = errors.New("object not found")
+
+// ObjectStore stores uploaded media. Clients upload and download objects
+// directly through the presigned URLs it hands out.
+type ObjectStore interface {
+	// PresignPut returns a URL that uploads an object of exactly size bytes
+	// until expiry has passed.
+	PresignPut(ctx context.Context, key, contentType string, size int64, expiry time.Duration) (string, error)
+	// PresignGet returns a URL that downloads an object until expiry has
+	// passed.
+	PresignGet(ctx context.Context, key string, expiry time.Duration) (string, error)
+	// Head returns the size and content type of an object. It returns
+	// ErrNotFound when the object does not exist.
+	Head(ctx context.Context, key string) (*ObjectInfo, error)
+	// Put stores an object of exactly size bytes read from body.
+	Put(ctx context.Context, key string, body io.ReadSeeker, size int64) error
+	// ReadPrefix returns the first n bytes of an object, or the whole object
+	// when it is shorter.
+	ReadPrefix(ctx context.Context, key string, n int64) ([]byte, error)
+	Delete(ctx context.Context, key string) error
+	// List returns every object in the store.
+	List(ctx context.Context) ([]Object, error)
+
+	// CreateMultipart starts an upload of an object in parts and returns
+	// the ID of the upload. Parts are uploaded through PresignPart and the
+	// object only appears once CompleteMultipart joins them.
+	CreateMultipart(ctx context.Context, key, contentType string) (string, error)
+	// PresignPart returns a URL that uploads part number of exactly size
+	// bytes until expiry has passed.
+	PresignPart(ctx context.Context, key, uploadID string, number int32, size int64, expiry time.Duration) (string, error)
+	// PutPart stores part number of a multipart upload from body.
+	PutPart(ctx context.Context, key, uploadID string, number int32, body io.ReadSeeker, size int64) (*Part, error)
+	// ListParts returns the parts uploaded so far, ordered by number. It
+	// returns ErrNotFound when the upload does not exist.
+	ListParts(ctx context.Context, key, uploadID string) ([]Part, error)
+	CompleteMultipart(ctx context.Context, key, uploadID string, parts []Part) error
+	AbortMultipart(ctx context.Context, key, uploadID string) error
+}
+
+type ObjectInfo struct {
+	Size        int64
+	ContentType string
+}
+
+type Object struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+}
+
+type Part struct {
+	Number int32
+	ETag   string
+	Size   int64
+}
+
+// Open connects to the storage backend selected in the configuration.
+func Open(ctx context.Context) (ObjectStore, error) {
+	switch config.StorageBackend {
+	case BackendS3:
+		awsConfig := awslib.NewConfig(
+			config.AWSRegion,
+			config.AWSAccessKey,
+			config.AWSSecretKey,
+			config.AWSS3Bucket,
+		)
+		awsConfig.Endpoint = config.AWSS3Endpoint
+		awsConfig.UsePathStyle = config.AWSS3UsePathStyle
+
+		client, err := awsConfig.NewS3Client(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return NewS3Store(client, config.AWSS3Bucket), nil
+
+	case BackendLocal:
+		secret := []byte(config.LocalStorageSecret)
+		if len(secret) == 0 {
+			// Signed URLs stop working on restart, which is fine for
+			// development.
+			log.Println("LOCAL_STORAGE_SECRET is not set, using a random secret")
+			secret = make([]byte, 32)
+			if _, err := rand.Read(secret); err != nil {
+				return nil, err
+			}
+		}
+		return NewLocalStore(config.LocalStorageDir, config.LocalStorageURL, secret)
+	}
+
+	return nil, fmt.Errorf("unknown storage backend %q", config.StorageBackend)
+}
+
+// PendingKey returns the key of an object of an upload in progress.
+func PendingKey(name string) string {
+	return PendingPrefix + name
+}
+
+// LegacyURL returns the public URL under which objects were referenced
+// before the bucket was private.
+func LegacyURL(key string) string {
+	return "https://" + config.AWSS3Bucket + ".s3.amazonaws.com/" + key
+}
+
+// KeyFromLegacyURL returns the key of an object from its public URL. It
+// reports false for URLs that point anywhere else.
+func KeyFromLegacyURL(rawURL string) (string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" || u.Host != config.AWSS3Bucket+".s3.amazonaws.com" {
+		return "", false
+	}
+
+	key := strings.TrimPrefix(u.Path, "/")
+	if key == "" || u.RawQuery != "" || u.Fragment != "" {
+		return "", false
+	}
+
+	return key, true
+}
@@ -0,0 +1,617 @@
+package store
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/pateldivyesh1323/futflare/server/internal/model"
+)
+
+// MemoryCapsuleStore keeps capsules in a map. It is meant for tests and local
+// development, nothing is persisted.
+type MemoryCapsuleStore struct {
+	mu       sync.RWMutex
+	capsules map[primitive.ObjectID]model.Capsule
+	// seriesEnded holds recurring capsules linked to no next instance, the
+	// in-memory counterpart of a null next_instance_id.
+	seriesEnded map[primitive.ObjectID]bool
+}
+
+func NewMemoryCapsuleStore() *MemoryCapsuleStore {
+	return &MemoryCapsuleStore{
+		capsules:    make(map[primitive.ObjectID]model.Capsule),
+		seriesEnded: make(map[primitive.ObjectID]bool),
+	}
+}
+
+func (s *MemoryCapsuleStore) Create(ctx context.Context, c *model.Capsule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c.ID.IsZero() {
+		c.ID = primitive.NewObjectID()
+	}
+	s.capsules[c.ID] = cloneCapsule(*c)
+
+	return nil
+}
+
+func (s *MemoryCapsuleStore) GetForUser(ctx context.Context, id primitive.ObjectID, userID, email string) (*model.Capsule, error) {
+	return s.getMember(id, userID, email, false)
+}
+
+func (s *MemoryCapsuleStore) GetForContributor(ctx context.Context, id primitive.ObjectID, userID, email string) (*model.Capsule, error) {
+	return s.getMember(id, userID, email, true)
+}
+
+func (s *MemoryCapsuleStore) getMember(id primitive.ObjectID, userID, email string, drafts bool) (*model.Capsule, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.capsules[id]
+	if !ok || !isMember(c, userID, email, drafts) {
+		return nil, ErrNotFound
+	}
+
+	capsule := cloneCapsule(c)
+	return &capsule, nil
+}
+
+func (s *MemoryCapsuleStore) List(ctx context.Context, filter ListFilter) ([]model.Capsule, int64, error) {
+	var search *regexp.Regexp
+	if filter.Search != "" {
+		var err error
+		search, err = regexp.Compile("(?i)" + filter.Search)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var matched []model.Capsule
+	for _, c := range s.capsules {
+		if !isMember(c, filter.UserID, filter.Email, true) {
+			continue
+		}
+		if search != nil && !search.MatchString(c.Title) && !search.MatchString(c.Description) {
+			continue
+		}
+		matched = append(matched, c)
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		if filter.Oldest {
+			return matched[i].CreatedAt.Before(matched[j].CreatedAt)
+		}
+		return matched[i].CreatedAt.After(matched[j].CreatedAt)
+	})
+
+	totalCount := int64(len(matched))
+
+	start := min(filter.Skip, totalCount)
+	end := totalCount
+	if filter.Limit > 0 {
+		end = min(start+filter.Limit, totalCount)
+	}
+
+	var capsules []model.Capsule
+	for _, c := range matched[start:end] {
+		capsules = append(capsules, cloneCapsule(c))
+	}
+
+	return capsules, totalCount, nil
+}
+
+func (s *MemoryCapsuleStore) Update(ctx context.Context, c *model.Capsule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.capsules[c.ID]
+	if !ok || existing.Creator != c.Creator || existing.Status != c.Status {
+		return ErrNotFound
+	}
+
+	existing.Title = c.Title
+	existing.Description = c.Description
+	existing.ParticipantEmails = c.ParticipantEmails
+	existing.ScheduledOpenDate = c.ScheduledOpenDate
+	existing.ContentItems = c.ContentItems
+	existing.NextRevealAt = c.NextRevealAt
+	var quorum *model.Quorum
+	if c.Quorum != nil {
+		// Approvals are written by SetApproval, an edit only drops the keys
+		// of removed participants.
+		quorum = &model.Quorum{Required: c.Quorum.Required, RequireDate: c.Quorum.RequireDate}
+		if existing.Quorum != nil {
+			for _, approval := range existing.Quorum.Approvals {
+				if isParticipant(*c, approval) {
+					quorum.Approvals = append(quorum.Approvals, approval)
+				}
+			}
+		}
+	}
+	existing.Quorum = quorum
+	s.capsules[c.ID] = cloneCapsule(existing)
+
+	return nil
+}
+
+func (s *MemoryCapsuleStore) AddContentItems(ctx context.Context, id primitive.ObjectID, items []model.ContentItem, maxItems, maxContributions int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.capsules[id]
+	if !ok || c.Status != model.CapsuleStatusDraft || len(c.ContentItems)+len(items) > maxItems {
+		return ErrNotFound
+	}
+
+	if contributor := items[0].ContributedBy; contributor != "" {
+		contributed := 0
+		for _, item := range c.ContentItems {
+			if item.ContributedBy == contributor {
+				contributed++
+			}
+		}
+		if contributed+len(items) > maxContributions {
+			return ErrNotFound
+		}
+	}
+
+	c.ContentItems = append(c.ContentItems, items...)
+	next := (&model.Capsule{ContentItems: items}).NextReveal(time.Now())
+	if next != nil && (c.NextRevealAt == nil || next.Before(*c.NextRevealAt)) {
+		c.NextRevealAt = next
+	}
+	s.capsules[id] = cloneCapsule(c)
+
+	return nil
+}
+
+func (s *MemoryCapsuleStore) UpdateStatus(ctx context.Context, id primitive.ObjectID, from, to model.CapsuleStatus) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.capsules[id]
+	if !ok || c.Status != from {
+		return ErrNotFound
+	}
+
+	c.Status = to
+	if to == model.CapsuleStatusOpened {
+		c.IsOpened = true
+	}
+	s.capsules[id] = c
+
+	return nil
+}
+
+func (s *MemoryCapsuleStore) Delete(ctx context.Context, id primitive.ObjectID, creator string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.capsules[id]
+	if !ok || c.Creator != creator {
+		return ErrNotFound
+	}
+	delete(s.capsules, id)
+	delete(s.seriesEnded, id)
+
+	return nil
+}
+
+func (s *MemoryCapsuleStore) OpenDue(ctx context.Context, now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var opened int64
+	for id, c := range s.capsules {
+		if !c.ReadyToOpen(now) {
+			continue
+		}
+		c.Status = model.CapsuleStatusOpened
+		c.IsOpened = true
+		s.capsules[id] = c
+		opened++
+	}
+
+	return opened, nil
+}
+
+func (s *MemoryCapsuleStore) SetApproval(ctx context.Context, id primitive.ObjectID, email string, approved bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.capsules[id]
+	if !ok || c.Status != model.CapsuleStatusSealed || c.Quorum == nil || !isParticipant(c, email) {
+		return ErrNotFound
+	}
+
+	quorum := *c.Quorum
+	quorum.Approvals = nil
+	for _, approval := range c.Quorum.Approvals {
+		if approval != email {
+			quorum.Approvals = append(quorum.Approvals, approval)
+		}
+	}
+	if approved {
+		quorum.Approvals = append(quorum.Approvals, email)
+	}
+	c.Quorum = &quorum
+	s.capsules[id] = c
+
+	return nil
+}
+
+func (s *MemoryCapsuleStore) SetDeadMansSwitch(ctx context.Context, id primitive.ObjectID, sw *model.DeadMansSwitch) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.capsules[id]
+	if !ok || (c.Status != model.CapsuleStatusDraft && c.Status != model.CapsuleStatusSealed) {
+		return ErrNotFound
+	}
+
+	if sw != nil {
+		state := *sw
+		sw = &state
+	}
+	c.DeadMansSwitch = sw
+	s.capsules[id] = c
+
+	return nil
+}
+
+func (s *MemoryCapsuleStore) ListDueReminders(ctx context.Context, now time.Time) ([]model.Capsule, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var capsules []model.Capsule
+	for _, c := range s.capsules {
+		sw := c.DeadMansSwitch
+		if c.Status != model.CapsuleStatusSealed || sw == nil || sw.Reminded || sw.RemindAt.After(now) {
+			continue
+		}
+		capsules = append(capsules, cloneCapsule(c))
+	}
+
+	return capsules, nil
+}
+
+func (s *MemoryCapsuleStore) MarkReminded(ctx context.Context, id primitive.ObjectID, remindAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.capsules[id]
+	if !ok || c.DeadMansSwitch == nil || !c.DeadMansSwitch.RemindAt.Equal(remindAt) {
+		return nil
+	}
+
+	state := *c.DeadMansSwitch
+	state.Reminded = true
+	c.DeadMansSwitch = &state
+	s.capsules[id] = c
+
+	return nil
+}
+
+func (s *MemoryCapsuleStore) AddUnlockedBy(ctx context.Context, id primitive.ObjectID, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.capsules[id]
+	if !ok || c.Lock == nil {
+		return ErrNotFound
+	}
+
+	if !c.Lock.IsUnlockedBy(userID) {
+		lock := *c.Lock
+		lock.UnlockedBy = append(append([]string(nil), c.Lock.UnlockedBy...), userID)
+		c.Lock = &lock
+		s.capsules[id] = c
+	}
+
+	return nil
+}
+
+func (s *MemoryCapsuleStore) RecordLocationUnlock(ctx context.Context, id primitive.ObjectID, unlock model.LocationUnlock) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.capsules[id]
+	if !ok {
+		return nil
+	}
+
+	for _, existing := range c.LocationUnlocks {
+		if existing.UserID == unlock.UserID {
+			return nil
+		}
+	}
+
+	c.LocationUnlocks = append(append([]model.LocationUnlock(nil), c.LocationUnlocks...), unlock)
+	s.capsules[id] = c
+
+	return nil
+}
+
+func (s *MemoryCapsuleStore) SetPredictionAnswer(ctx context.Context, id, predictionID primitive.ObjectID, answer model.PredictionAnswer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.capsules[id]
+	if !ok || (c.Status != model.CapsuleStatusDraft && c.Status != model.CapsuleStatusSealed) {
+		return ErrNotFound
+	}
+
+	return updatePrediction(&c, predictionID, func(prediction *model.PredictionContent) bool {
+		if existing := prediction.AnswerBy(answer.ParticipantEmail); existing != nil {
+			*existing = answer
+		} else {
+			prediction.Answers = append(prediction.Answers, answer)
+		}
+		s.capsules[id] = c
+		return true
+	})
+}
+
+func (s *MemoryCapsuleStore) SetPredictionOutcome(ctx context.Context, id, predictionID primitive.ObjectID, email string, cameTrue bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.capsules[id]
+	if !ok || c.Status != model.CapsuleStatusOpened {
+		return ErrNotFound
+	}
+
+	return updatePrediction(&c, predictionID, func(prediction *model.PredictionContent) bool {
+		answer := prediction.AnswerBy(email)
+		if answer == nil {
+			return false
+		}
+		answer.CameTrue = &cameTrue
+		s.capsules[id] = c
+		return true
+	})
+}
+
+func (s *MemoryCapsuleStore) SetGoalAssessment(ctx context.Context, id, goalID primitive.ObjectID, assessment model.GoalAssessment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.capsules[id]
+	if !ok || c.Status != model.CapsuleStatusOpened {
+		return ErrNotFound
+	}
+
+	// Copy the checklist, capsules handed out earlier share its content.
+	items := append([]model.ContentItem(nil), c.ContentItems...)
+	item, goal := model.FindGoal(items, goalID)
+	if goal == nil {
+		return ErrNotFound
+	}
+
+	checklist := *item.Content.(*model.GoalsContent)
+	checklist.Goals = append([]model.Goal(nil), checklist.Goals...)
+	for i := range checklist.Goals {
+		if checklist.Goals[i].ID != goalID {
+			continue
+		}
+
+		updated := &checklist.Goals[i]
+		updated.Assessments = append([]model.GoalAssessment(nil), updated.Assessments...)
+		if existing := updated.AssessmentBy(assessment.ParticipantEmail); existing != nil {
+			*existing = assessment
+		} else {
+			updated.Assessments = append(updated.Assessments, assessment)
+		}
+	}
+	item.Content = &checklist
+
+	c.ContentItems = items
+	s.capsules[id] = c
+
+	return nil
+}
+
+// updatePrediction applies update to a copy of the prediction, so capsules
+// handed out earlier keep their content. update reports whether it changed
+// the prediction.
+func updatePrediction(c *model.Capsule, predictionID primitive.ObjectID, update func(*model.PredictionContent) bool) error {
+	items := append([]model.ContentItem(nil), c.ContentItems...)
+	item, prediction := model.FindPrediction(items, predictionID)
+	if prediction == nil {
+		return ErrNotFound
+	}
+
+	updated := *prediction
+	updated.Answers = append([]model.PredictionAnswer(nil), prediction.Answers...)
+	item.Content = &updated
+	c.ContentItems = items
+
+	if !update(&updated) {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *MemoryCapsuleStore) SetLinkPreviews(ctx context.Context, id primitive.ObjectID, previews map[string]*model.LinkPreview) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.capsules[id]
+	if !ok {
+		return ErrNotFound
+	}
+
+	// Copy the items, capsules handed out earlier keep their content.
+	items := append([]model.ContentItem(nil), c.ContentItems...)
+	for i := range items {
+		link, ok := items[i].Content.(*model.LinkContent)
+		if !ok || link.Preview != nil || previews[link.URL] == nil {
+			continue
+		}
+
+		updated := *link
+		updated.Preview = previews[link.URL]
+		items[i].Content = &updated
+	}
+	c.ContentItems = items
+	s.capsules[id] = c
+
+	return nil
+}
+
+func (s *MemoryCapsuleStore) ListUploads(ctx context.Context) ([]model.Upload, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var uploads []model.Upload
+	for _, c := range s.capsules {
+		uploads = append(uploads, capsuleUploads(c)...)
+	}
+
+	return uploads, nil
+}
+
+func (s *MemoryCapsuleStore) UploadReferenced(ctx context.Context, upload model.Upload) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, c := range s.capsules {
+		for _, u := range capsuleUploads(c) {
+			if (upload.ObjectKey != "" && u.ObjectKey == upload.ObjectKey) || (upload.URL != "" && u.URL == upload.URL) {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+func (s *MemoryCapsuleStore) AdvanceReveals(ctx context.Context, now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var advanced int64
+	for id, c := range s.capsules {
+		if c.Status != model.CapsuleStatusOpened || c.NextRevealAt == nil || c.NextRevealAt.After(now) {
+			continue
+		}
+		c.NextRevealAt = c.NextReveal(now)
+		s.capsules[id] = c
+		advanced++
+	}
+
+	return advanced, nil
+}
+
+func (s *MemoryCapsuleStore) ListPendingRecurrences(ctx context.Context) ([]model.Capsule, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var capsules []model.Capsule
+	for _, c := range s.capsules {
+		if c.Status != model.CapsuleStatusOpened || c.Recurrence == nil {
+			continue
+		}
+		if c.NextInstanceID != nil || s.seriesEnded[c.ID] {
+			continue
+		}
+		capsules = append(capsules, cloneCapsule(c))
+	}
+
+	return capsules, nil
+}
+
+func (s *MemoryCapsuleStore) LinkNextInstance(ctx context.Context, id primitive.ObjectID, next *model.Capsule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.capsules[id]
+	if !ok || c.NextInstanceID != nil || s.seriesEnded[id] {
+		return ErrNotFound
+	}
+
+	if next == nil {
+		s.seriesEnded[id] = true
+		return nil
+	}
+
+	if next.ID.IsZero() {
+		next.ID = primitive.NewObjectID()
+	}
+	nextID := next.ID
+	c.NextInstanceID = &nextID
+	s.capsules[id] = c
+	s.capsules[next.ID] = cloneCapsule(*next)
+
+	return nil
+}
+
+func (s *MemoryCapsuleStore) ListSeries(ctx context.Context, seriesID primitive.ObjectID, userID, email string) ([]model.Capsule, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var capsules []model.Capsule
+	for _, c := range s.capsules {
+		if c.SeriesID != nil && *c.SeriesID == seriesID && isMember(c, userID, email, false) {
+			capsules = append(capsules, cloneCapsule(c))
+		}
+	}
+
+	sort.Slice(capsules, func(i, j int) bool {
+		return capsules[i].ScheduledOpenDate.Before(capsules[j].ScheduledOpenDate)
+	})
+
+	return capsules, nil
+}
+
+// isMember reports whether the user created or participates in the capsule.
+// Participants are only members of drafts with drafts set.
+func isMember(c model.Capsule, userID, email string, drafts bool) bool {
+	if c.Creator == userID {
+		return true
+	}
+	if c.Status == model.CapsuleStatusDraft && !drafts {
+		return false
+	}
+	return isParticipant(c, email)
+}
+
+func isParticipant(c model.Capsule, email string) bool {
+	for _, participant := range c.ParticipantEmails {
+		if participant == email {
+			return true
+		}
+	}
+	return false
+}
+
+func cloneCapsule(c model.Capsule) model.Capsule {
+	c.ParticipantEmails = append([]string(nil), c.ParticipantEmails...)
+	c.ContentItems = append([]model.ContentItem(nil), c.ContentItems...)
+	c.LocationUnlocks = append([]model.LocationUnlock(nil), c.LocationUnlocks...)
+	if c.Lock != nil {
+		lock := *c.Lock
+		lock.UnlockedBy = append([]string(nil), c.Lock.UnlockedBy...)
+		c.Lock = &lock
+	}
+	if c.DeadMansSwitch != nil {
+		sw := *c.DeadMansSwitch
+		c.DeadMansSwitch = &sw
+	}
+	if c.Quorum != nil {
+		quorum := *c.Quorum
+		quorum.Approvals = append([]string(nil), c.Quorum.Approvals...)
+		c.Quorum = &quorum
+	}
+	return c
+}
@@ -0,0 +1,127 @@
+package store
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/pateldivyesh1323/futflare/server/internal/model"
+)
+
+// MemoryUploadSessionStore keeps upload sessions in a map. It is meant for
+// tests and local development, nothing is persisted.
+type MemoryUploadSessionStore struct {
+	mu       sync.RWMutex
+	sessions map[primitive.ObjectID]model.UploadSession
+}
+
+func NewMemoryUploadSessionStore() *MemoryUploadSessionStore {
+	return &MemoryUploadSessionStore{
+		sessions: make(map[primitive.ObjectID]model.UploadSession),
+	}
+}
+
+func (s *MemoryUploadSessionStore) CreateUploadSession(ctx context.Context, session *model.UploadSession) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if session.ID.IsZero() {
+		session.ID = primitive.NewObjectID()
+	}
+	if session.Parts == nil {
+		session.Parts = []model.UploadPart{}
+	}
+	s.sessions[session.ID] = cloneUploadSession(*session)
+
+	return nil
+}
+
+func (s *MemoryUploadSessionStore) GetUploadSession(ctx context.Context, id primitive.ObjectID, userID string) (*model.UploadSession, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	session, ok := s.sessions[id]
+	if !ok || session.UserID != userID {
+		return nil, ErrNotFound
+	}
+
+	session = cloneUploadSession(session)
+	return &session, nil
+}
+
+func (s *MemoryUploadSessionStore) SetUploadParts(ctx context.Context, id primitive.ObjectID, parts []model.UploadPart) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[id]
+	if !ok || session.Status != model.UploadSessionUploading {
+		return ErrNotFound
+	}
+
+	session.Parts = slices.Clone(parts)
+	if session.Parts == nil {
+		session.Parts = []model.UploadPart{}
+	}
+	session.UpdatedAt = time.Now()
+	s.sessions[id] = session
+
+	return nil
+}
+
+func (s *MemoryUploadSessionStore) SetUploadProgress(ctx context.Context, id primitive.ObjectID, prevParts int, prevTailSize int64, parts []model.UploadPart, tailSize int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[id]
+	if !ok || session.Status != model.UploadSessionUploading || len(session.Parts) != prevParts || session.TailSize != prevTailSize {
+		return ErrNotFound
+	}
+
+	session.Parts = slices.Clone(parts)
+	if session.Parts == nil {
+		session.Parts = []model.UploadPart{}
+	}
+	session.TailSize = tailSize
+	session.UpdatedAt = time.Now()
+	s.sessions[id] = session
+
+	return nil
+}
+
+func (s *MemoryUploadSessionStore) UpdateUploadStatus(ctx context.Context, id primitive.ObjectID, from, to model.UploadSessionStatus) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[id]
+	if !ok || session.Status != from {
+		return ErrNotFound
+	}
+
+	session.Status = to
+	session.UpdatedAt = time.Now()
+	s.sessions[id] = session
+
+	return nil
+}
+
+func (s *MemoryUploadSessionStore) ListExpiredUploadSessions(ctx context.Context, now time.Time) ([]model.UploadSession, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var sessions []model.UploadSession
+	for _, session := range s.sessions {
+		if session.Status == model.UploadSessionUploading && !session.ExpiresAt.After(now) {
+			sessions = append(sessions, cloneUploadSession(session))
+		}
+	}
+
+	return sessions, nil
+}
+
+func cloneUploadSession(session model.UploadSession) model.UploadSession {
+	session.Parts = slices.Clone(session.Parts)
+	return session
+}
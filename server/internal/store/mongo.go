@@ -0,0 +1,798 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/pateldivyesh1323/futflare/server/internal/model"
+)
+
+type MongoCapsuleStore struct {
+	collection *mongo.Collection
+}
+
+func NewMongoCapsuleStore(db *mongo.Database) *MongoCapsuleStore {
+	return &MongoCapsuleStore{
+		collection: db.Collection("capsule"),
+	}
+}
+
+func (s *MongoCapsuleStore) EnsureIndexes(ctx context.Context) error {
+	_, err := s.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "status", Value: 1},
+				{Key: "scheduled_open_date", Value: 1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "status", Value: 1},
+				{Key: "next_reveal_at", Value: 1},
+			},
+		},
+	})
+	return err
+}
+
+// MigrateStatus backfills the status field on capsules created before the
+// capsule lifecycle existed.
+func (s *MongoCapsuleStore) MigrateStatus(ctx context.Context) (int64, error) {
+	var migrated int64
+
+	for isOpened, status := range map[bool]model.CapsuleStatus{
+		true:  model.CapsuleStatusOpened,
+		false: model.CapsuleStatusSealed,
+	} {
+		result, err := s.collection.UpdateMany(ctx, bson.M{
+			"status":    bson.M{"$exists": false},
+			"is_opened": isOpened,
+		}, bson.M{
+			"$set": bson.M{"status": status},
+		})
+		if err != nil {
+			return migrated, err
+		}
+		migrated += result.ModifiedCount
+	}
+
+	return migrated, nil
+}
+
+func (s *MongoCapsuleStore) Create(ctx context.Context, c *model.Capsule) error {
+	normalizeArrays(c)
+
+	_, err := s.collection.InsertOne(ctx, c)
+	return err
+}
+
+func (s *MongoCapsuleStore) GetForUser(ctx context.Context, id primitive.ObjectID, userID, email string) (*model.Capsule, error) {
+	return s.getMember(ctx, id, memberFilter(userID, email, false))
+}
+
+func (s *MongoCapsuleStore) GetForContributor(ctx context.Context, id primitive.ObjectID, userID, email string) (*model.Capsule, error) {
+	return s.getMember(ctx, id, memberFilter(userID, email, true))
+}
+
+func (s *MongoCapsuleStore) getMember(ctx context.Context, id primitive.ObjectID, members []bson.M) (*model.Capsule, error) {
+	var capsule model.Capsule
+	err := s.collection.FindOne(ctx, bson.M{
+		"_id": id,
+		"$or": members,
+	}).Decode(&capsule)
+
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &capsule, nil
+}
+
+func (s *MongoCapsuleStore) List(ctx context.Context, filter ListFilter) ([]model.Capsule, int64, error) {
+	query := bson.M{
+		"$or": memberFilter(filter.UserID, filter.Email, true),
+	}
+
+	if filter.Search != "" {
+		query = bson.M{
+			"$and": []bson.M{
+				query,
+				{
+					"$or": []bson.M{
+						{"title": bson.M{"$regex": filter.Search, "$options": "i"}},
+						{"description": bson.M{"$regex": filter.Search, "$options": "i"}},
+					},
+				},
+			},
+		}
+	}
+
+	totalCount, err := s.collection.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sortOrder := -1
+	if filter.Oldest {
+		sortOrder = 1
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: sortOrder}}).
+		SetSkip(filter.Skip).
+		SetLimit(filter.Limit)
+
+	cursor, err := s.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var capsules []model.Capsule
+	if err := cursor.All(ctx, &capsules); err != nil {
+		return nil, 0, err
+	}
+
+	return capsules, totalCount, nil
+}
+
+func (s *MongoCapsuleStore) Update(ctx context.Context, c *model.Capsule) error {
+	normalizeArrays(c)
+
+	filter := bson.M{
+		"_id":     c.ID,
+		"creator": c.Creator,
+		"status":  c.Status,
+	}
+
+	set := bson.M{
+		"title":               c.Title,
+		"description":         c.Description,
+		"participant_emails":  c.ParticipantEmails,
+		"scheduled_open_date": c.ScheduledOpenDate,
+		"content_items":       c.ContentItems,
+		"next_reveal_at":      c.NextRevealAt,
+	}
+	update := bson.M{"$set": set}
+
+	// Approvals are written by SetApproval, an edit only drops the keys of
+	// removed participants. A capsule without quorum must not store
+	// quorum: null, see OpenDue.
+	if c.Quorum != nil {
+		set["quorum.required"] = c.Quorum.Required
+		set["quorum.require_date"] = c.Quorum.RequireDate
+		update["$pull"] = bson.M{"quorum.approvals": bson.M{"$nin": c.ParticipantEmails}}
+	} else {
+		update["$unset"] = bson.M{"quorum": ""}
+	}
+
+	result, err := s.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *MongoCapsuleStore) AddContentItems(ctx context.Context, id primitive.ObjectID, items []model.ContentItem, maxItems, maxContributions int) error {
+	// The capsule has room for the new items as long as the array has no
+	// element at index maxItems-len(items).
+	filter := bson.M{
+		"_id":    id,
+		"status": model.CapsuleStatusDraft,
+		fmt.Sprintf("content_items.%d", maxItems-len(items)): bson.M{"$exists": false},
+	}
+
+	// Contributions are counted in the filter too, so concurrent requests of
+	// one participant cannot both pass a count read earlier.
+	if contributor := items[0].ContributedBy; contributor != "" {
+		contributed := bson.M{"$filter": bson.M{
+			"input": bson.M{"$ifNull": bson.A{"$content_items", bson.A{}}},
+			"cond":  bson.M{"$eq": bson.A{"$$this.contributed_by", contributor}},
+		}}
+		filter["$expr"] = bson.M{"$lte": bson.A{bson.M{"$size": contributed}, maxContributions - len(items)}}
+	}
+
+	update := bson.M{
+		"$push": bson.M{
+			"content_items": bson.M{"$each": items},
+		},
+	}
+
+	next := (&model.Capsule{ContentItems: items}).NextReveal(time.Now())
+	if next != nil {
+		update["$min"] = bson.M{"next_reveal_at": next}
+	}
+
+	result, err := s.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *MongoCapsuleStore) UpdateStatus(ctx context.Context, id primitive.ObjectID, from, to model.CapsuleStatus) error {
+	set := bson.M{"status": to}
+	if to == model.CapsuleStatusOpened {
+		set["is_opened"] = true
+	}
+
+	result, err := s.collection.UpdateOne(ctx, bson.M{
+		"_id":    id,
+		"status": from,
+	}, bson.M{
+		"$set": set,
+	})
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *MongoCapsuleStore) Delete(ctx context.Context, id primitive.ObjectID, creator string) error {
+	result, err := s.collection.DeleteOne(ctx, bson.M{
+		"_id":     id,
+		"creator": creator,
+	})
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *MongoCapsuleStore) OpenDue(ctx context.Context, now time.Time) (int64, error) {
+	quorumReached := bson.M{
+		"$gte": bson.A{
+			bson.M{"$size": bson.M{"$ifNull": bson.A{"$quorum.approvals", bson.A{}}}},
+			"$quorum.required",
+		},
+	}
+
+	filter := bson.M{
+		"status": model.CapsuleStatusSealed,
+		"$or": []bson.M{
+			{
+				"quorum":              nil,
+				"dead_mans_switch":    bson.M{"$exists": false},
+				"scheduled_open_date": bson.M{"$lte": now},
+			},
+			{
+				"dead_mans_switch.opens_at": bson.M{"$lte": now},
+			},
+			{
+				"quorum.require_date": false,
+				"$expr":               quorumReached,
+			},
+			{
+				"quorum.require_date": true,
+				"scheduled_open_date": bson.M{"$lte": now},
+				"$expr":               quorumReached,
+			},
+		},
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"status":    model.CapsuleStatusOpened,
+			"is_opened": true,
+		},
+	}
+
+	result, err := s.collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
+
+func (s *MongoCapsuleStore) SetApproval(ctx context.Context, id primitive.ObjectID, email string, approved bool) error {
+	filter := bson.M{
+		"_id":                id,
+		"status":             model.CapsuleStatusSealed,
+		"quorum":             bson.M{"$exists": true},
+		"participant_emails": email,
+	}
+
+	update := bson.M{"$pull": bson.M{"quorum.approvals": email}}
+	if approved {
+		update = bson.M{"$addToSet": bson.M{"quorum.approvals": email}}
+	}
+
+	result, err := s.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *MongoCapsuleStore) SetDeadMansSwitch(ctx context.Context, id primitive.ObjectID, sw *model.DeadMansSwitch) error {
+	result, err := s.collection.UpdateOne(ctx, bson.M{
+		"_id":    id,
+		"status": bson.M{"$in": bson.A{model.CapsuleStatusDraft, model.CapsuleStatusSealed}},
+	}, bson.M{
+		"$set": bson.M{"dead_mans_switch": sw},
+	})
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *MongoCapsuleStore) ListDueReminders(ctx context.Context, now time.Time) ([]model.Capsule, error) {
+	cursor, err := s.collection.Find(ctx, bson.M{
+		"status":                     model.CapsuleStatusSealed,
+		"dead_mans_switch.reminded":  false,
+		"dead_mans_switch.remind_at": bson.M{"$lte": now},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var capsules []model.Capsule
+	if err := cursor.All(ctx, &capsules); err != nil {
+		return nil, err
+	}
+
+	return capsules, nil
+}
+
+func (s *MongoCapsuleStore) MarkReminded(ctx context.Context, id primitive.ObjectID, remindAt time.Time) error {
+	_, err := s.collection.UpdateOne(ctx, bson.M{
+		"_id":                        id,
+		"dead_mans_switch.remind_at": remindAt,
+	}, bson.M{
+		"$set": bson.M{"dead_mans_switch.reminded": true},
+	})
+	return err
+}
+
+func (s *MongoCapsuleStore) AddUnlockedBy(ctx context.Context, id primitive.ObjectID, userID string) error {
+	result, err := s.collection.UpdateOne(ctx, bson.M{
+		"_id":  id,
+		"lock": bson.M{"$exists": true},
+	}, bson.M{
+		"$addToSet": bson.M{"lock.unlocked_by": userID},
+	})
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *MongoCapsuleStore) RecordLocationUnlock(ctx context.Context, id primitive.ObjectID, unlock model.LocationUnlock) error {
+	_, err := s.collection.UpdateOne(ctx, bson.M{
+		"_id":                      id,
+		"location_unlocks.user_id": bson.M{"$ne": unlock.UserID},
+	}, bson.M{
+		"$push": bson.M{"location_unlocks": unlock},
+	})
+	return err
+}
+
+func (s *MongoCapsuleStore) SetPredictionAnswer(ctx context.Context, id, predictionID primitive.ObjectID, answer model.PredictionAnswer) error {
+	// Replace an existing answer first, the answer is only appended when the
+	// member has not answered yet. Both updates are guarded by their filter,
+	// so concurrent answers of the same member cannot both be appended.
+	result, err := s.collection.UpdateOne(ctx, bson.M{
+		"_id":    id,
+		"status": bson.M{"$in": bson.A{model.CapsuleStatusDraft, model.CapsuleStatusSealed}},
+		"content_items": bson.M{"$elemMatch": bson.M{
+			"type":                              model.ContentTypePrediction,
+			"content.id":                        predictionID,
+			"content.answers.participant_email": answer.ParticipantEmail,
+		}},
+	}, bson.M{
+		"$set": bson.M{"content_items.$[item].content.answers.$[answer]": answer},
+	}, options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{
+		bson.M{"item.type": model.ContentTypePrediction, "item.content.id": predictionID},
+		bson.M{"answer.participant_email": answer.ParticipantEmail},
+	}}))
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount > 0 {
+		return nil
+	}
+
+	result, err = s.collection.UpdateOne(ctx, bson.M{
+		"_id":    id,
+		"status": bson.M{"$in": bson.A{model.CapsuleStatusDraft, model.CapsuleStatusSealed}},
+		"content_items": bson.M{"$elemMatch": bson.M{
+			"type":                              model.ContentTypePrediction,
+			"content.id":                        predictionID,
+			"content.answers.participant_email": bson.M{"$ne": answer.ParticipantEmail},
+		}},
+	}, bson.M{
+		"$push": bson.M{"content_items.$[item].content.answers": answer},
+	}, options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{
+		bson.M{"item.type": model.ContentTypePrediction, "item.content.id": predictionID},
+	}}))
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *MongoCapsuleStore) SetPredictionOutcome(ctx context.Context, id, predictionID primitive.ObjectID, email string, cameTrue bool) error {
+	result, err := s.collection.UpdateOne(ctx, bson.M{
+		"_id":    id,
+		"status": model.CapsuleStatusOpened,
+		"content_items": bson.M{"$elemMatch": bson.M{
+			"type":                              model.ContentTypePrediction,
+			"content.id":                        predictionID,
+			"content.answers.participant_email": email,
+		}},
+	}, bson.M{
+		"$set": bson.M{"content_items.$[item].content.answers.$[answer].came_true": cameTrue},
+	}, options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{
+		bson.M{"item.type": model.ContentTypePrediction, "item.content.id": predictionID},
+		bson.M{"answer.participant_email": email},
+	}}))
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *MongoCapsuleStore) SetGoalAssessment(ctx context.Context, id, goalID primitive.ObjectID, assessment model.GoalAssessment) error {
+	goalFilter := bson.M{"goal.id": goalID}
+	itemFilter := bson.M{"item.type": model.ContentTypeGoals, "item.content.goals.id": goalID}
+
+	// Like answers to predictions, an existing assessment is replaced and a
+	// new one is only appended when the member has not assessed the goal yet.
+	result, err := s.collection.UpdateOne(ctx, bson.M{
+		"_id":    id,
+		"status": model.CapsuleStatusOpened,
+		"content_items": bson.M{"$elemMatch": bson.M{
+			"type": model.ContentTypeGoals,
+			"content.goals": bson.M{"$elemMatch": bson.M{
+				"id":                            goalID,
+				"assessments.participant_email": assessment.ParticipantEmail,
+			}},
+		}},
+	}, bson.M{
+		"$set": bson.M{"content_items.$[item].content.goals.$[goal].assessments.$[assessment]": assessment},
+	}, options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{
+		itemFilter,
+		goalFilter,
+		bson.M{"assessment.participant_email": assessment.ParticipantEmail},
+	}}))
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount > 0 {
+		return nil
+	}
+
+	result, err = s.collection.UpdateOne(ctx, bson.M{
+		"_id":    id,
+		"status": model.CapsuleStatusOpened,
+		"content_items": bson.M{"$elemMatch": bson.M{
+			"type": model.ContentTypeGoals,
+			"content.goals": bson.M{"$elemMatch": bson.M{
+				"id":                            goalID,
+				"assessments.participant_email": bson.M{"$ne": assessment.ParticipantEmail},
+			}},
+		}},
+	}, bson.M{
+		"$push": bson.M{"content_items.$[item].content.goals.$[goal].assessments": assessment},
+	}, options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{
+		itemFilter,
+		goalFilter,
+	}}))
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *MongoCapsuleStore) SetLinkPreviews(ctx context.Context, id primitive.ObjectID, previews map[string]*model.LinkPreview) error {
+	set := bson.M{}
+	filters := []interface{}{}
+	for url, preview := range previews {
+		name := fmt.Sprintf("link%d", len(filters))
+		set[fmt.Sprintf("content_items.$[%s].content.preview", name)] = preview
+		filters = append(filters, bson.M{
+			name + ".type":            model.ContentTypeLink,
+			name + ".content.url":     url,
+			name + ".content.preview": bson.M{"$exists": false},
+		})
+	}
+
+	if len(set) == 0 {
+		return nil
+	}
+
+	result, err := s.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set": set,
+	}, options.Update().SetArrayFilters(options.ArrayFilters{Filters: filters}))
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *MongoCapsuleStore) ListUploads(ctx context.Context) ([]model.Upload, error) {
+	cursor, err := s.collection.Find(ctx, bson.M{
+		"content_items": bson.M{"$elemMatch": bson.M{
+			"type": bson.M{"$in": model.UploadedContentTypes()},
+			"$or": []bson.M{
+				{"content.object_key": bson.M{"$exists": true}},
+				{"content.url": bson.M{"$exists": true}},
+			},
+		}},
+	}, options.Find().SetProjection(bson.M{"content_items": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var uploads []model.Upload
+	for cursor.Next(ctx) {
+		var capsule model.Capsule
+		if err := cursor.Decode(&capsule); err != nil {
+			return nil, err
+		}
+		uploads = append(uploads, capsuleUploads(capsule)...)
+	}
+
+	return uploads, cursor.Err()
+}
+
+func (s *MongoCapsuleStore) UploadReferenced(ctx context.Context, upload model.Upload) (bool, error) {
+	// Only items of uploaded types count, the URL of a link item is an
+	// arbitrary external URL.
+	uploaded := func(field, value string) bson.M {
+		return bson.M{"content_items": bson.M{"$elemMatch": bson.M{
+			"type": bson.M{"$in": model.UploadedContentTypes()},
+			field:  value,
+		}}}
+	}
+
+	var refs []bson.M
+	if upload.ObjectKey != "" {
+		refs = append(refs, uploaded("content.object_key", upload.ObjectKey))
+	}
+	if upload.URL != "" {
+		refs = append(refs, uploaded("content.url", upload.URL))
+	}
+	if len(refs) == 0 {
+		return false, nil
+	}
+
+	count, err := s.collection.CountDocuments(ctx, bson.M{"$or": refs}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (s *MongoCapsuleStore) AdvanceReveals(ctx context.Context, now time.Time) (int64, error) {
+	cursor, err := s.collection.Find(ctx, bson.M{
+		"status":         model.CapsuleStatusOpened,
+		"next_reveal_at": bson.M{"$lte": now},
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var advanced int64
+	for cursor.Next(ctx) {
+		var capsule model.Capsule
+		if err := cursor.Decode(&capsule); err != nil {
+			return advanced, err
+		}
+
+		update := bson.M{"$unset": bson.M{"next_reveal_at": ""}}
+		if next := capsule.NextReveal(now); next != nil {
+			update = bson.M{"$set": bson.M{"next_reveal_at": next}}
+		}
+
+		if _, err := s.collection.UpdateByID(ctx, capsule.ID, update); err != nil {
+			return advanced, err
+		}
+		advanced++
+	}
+
+	return advanced, cursor.Err()
+}
+
+func (s *MongoCapsuleStore) ListPendingRecurrences(ctx context.Context) ([]model.Capsule, error) {
+	cursor, err := s.collection.Find(ctx, bson.M{
+		"status":           model.CapsuleStatusOpened,
+		"recurrence":       bson.M{"$exists": true},
+		"next_instance_id": bson.M{"$exists": false},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var capsules []model.Capsule
+	if err := cursor.All(ctx, &capsules); err != nil {
+		return nil, err
+	}
+
+	return capsules, nil
+}
+
+func (s *MongoCapsuleStore) LinkNextInstance(ctx context.Context, id primitive.ObjectID, next *model.Capsule) error {
+	var nextID *primitive.ObjectID
+	if next != nil {
+		nextID = &next.ID
+	}
+
+	result, err := s.collection.UpdateOne(ctx, bson.M{
+		"_id":              id,
+		"next_instance_id": bson.M{"$exists": false},
+	}, bson.M{
+		"$set": bson.M{"next_instance_id": nextID},
+	})
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+
+	if next == nil {
+		return nil
+	}
+
+	// Release the link when the insert fails, so the next run of the
+	// scheduler spawns the instance again.
+	if err := s.Create(ctx, next); err != nil {
+		_, unlinkErr := s.collection.UpdateOne(ctx, bson.M{
+			"_id":              id,
+			"next_instance_id": next.ID,
+		}, bson.M{
+			"$unset": bson.M{"next_instance_id": ""},
+		})
+		if unlinkErr != nil {
+			return fmt.Errorf("%w, and unlinking it failed: %v", err, unlinkErr)
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (s *MongoCapsuleStore) ListSeries(ctx context.Context, seriesID primitive.ObjectID, userID, email string) ([]model.Capsule, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "scheduled_open_date", Value: 1}})
+
+	cursor, err := s.collection.Find(ctx, bson.M{
+		"series_id": seriesID,
+		"$or":       memberFilter(userID, email, false),
+	}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var capsules []model.Capsule
+	if err := cursor.All(ctx, &capsules); err != nil {
+		return nil, err
+	}
+
+	return capsules, nil
+}
+
+// normalizeArrays replaces nil slices that are later modified with $push,
+// $addToSet or $pull, since those operators fail on null fields.
+func normalizeArrays(c *model.Capsule) {
+	if c.ContentItems == nil {
+		c.ContentItems = []model.ContentItem{}
+	}
+	if c.Quorum != nil && c.Quorum.Approvals == nil {
+		c.Quorum.Approvals = []string{}
+	}
+	if c.Lock != nil && c.Lock.UnlockedBy == nil {
+		c.Lock.UnlockedBy = []string{}
+	}
+	for _, item := range c.ContentItems {
+		if prediction, ok := item.Content.(*model.PredictionContent); ok && prediction.Answers == nil {
+			prediction.Answers = []model.PredictionAnswer{}
+		}
+		if goals, ok := item.Content.(*model.GoalsContent); ok {
+			for i := range goals.Goals {
+				if goals.Goals[i].Assessments == nil {
+					goals.Goals[i].Assessments = []model.GoalAssessment{}
+				}
+			}
+		}
+	}
+}
+
+// capsuleUploads returns the uploads of the content items of a capsule.
+func capsuleUploads(c model.Capsule) []model.Upload {
+	var uploads []model.Upload
+	for _, item := range c.ContentItems {
+		if content, ok := item.Content.(model.UploadedContent); ok {
+			uploads = append(uploads, *content.GetUpload())
+		}
+	}
+	return uploads
+}
+
+// memberFilter matches the capsules a user created or participates in.
+// Participants only match drafts with drafts set.
+func memberFilter(userID, email string, drafts bool) []bson.M {
+	participant := bson.M{"participant_emails": bson.M{"$elemMatch": bson.M{"$eq": email}}}
+	if !drafts {
+		participant["status"] = bson.M{"$ne": model.CapsuleStatusDraft}
+	}
+
+	return []bson.M{
+		{"creator": userID},
+		participant,
+	}
+}
@@ -0,0 +1,142 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/pateldivyesh1323/futflare/server/internal/model"
+)
+
+type MongoUploadSessionStore struct {
+	collection *mongo.Collection
+}
+
+func NewMongoUploadSessionStore(db *mongo.Database) *MongoUploadSessionStore {
+	return &MongoUploadSessionStore{
+		collection: db.Collection("upload_session"),
+	}
+}
+
+func (s *MongoUploadSessionStore) EnsureIndexes(ctx context.Context) error {
+	_, err := s.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "status", Value: 1},
+			{Key: "expires_at", Value: 1},
+		},
+	})
+	return err
+}
+
+func (s *MongoUploadSessionStore) CreateUploadSession(ctx context.Context, session *model.UploadSession) error {
+	if session.Parts == nil {
+		session.Parts = []model.UploadPart{}
+	}
+
+	_, err := s.collection.InsertOne(ctx, session)
+	return err
+}
+
+func (s *MongoUploadSessionStore) GetUploadSession(ctx context.Context, id primitive.ObjectID, userID string) (*model.UploadSession, error) {
+	var session model.UploadSession
+	err := s.collection.FindOne(ctx, bson.M{
+		"_id":     id,
+		"user_id": userID,
+	}).Decode(&session)
+
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+func (s *MongoUploadSessionStore) SetUploadParts(ctx context.Context, id primitive.ObjectID, parts []model.UploadPart) error {
+	if parts == nil {
+		parts = []model.UploadPart{}
+	}
+
+	result, err := s.collection.UpdateOne(ctx, bson.M{
+		"_id":    id,
+		"status": model.UploadSessionUploading,
+	}, bson.M{
+		"$set": bson.M{"parts": parts, "updated_at": time.Now()},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *MongoUploadSessionStore) SetUploadProgress(ctx context.Context, id primitive.ObjectID, prevParts int, prevTailSize int64, parts []model.UploadPart, tailSize int64) error {
+	if parts == nil {
+		parts = []model.UploadPart{}
+	}
+
+	// tail_size is omitted while it is zero.
+	var prevTail interface{} = prevTailSize
+	if prevTailSize == 0 {
+		prevTail = bson.M{"$in": bson.A{0, nil}}
+	}
+
+	result, err := s.collection.UpdateOne(ctx, bson.M{
+		"_id":       id,
+		"status":    model.UploadSessionUploading,
+		"parts":     bson.M{"$size": prevParts},
+		"tail_size": prevTail,
+	}, bson.M{
+		"$set": bson.M{"parts": parts, "tail_size": tailSize, "updated_at": time.Now()},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *MongoUploadSessionStore) UpdateUploadStatus(ctx context.Context, id primitive.ObjectID, from, to model.UploadSessionStatus) error {
+	result, err := s.collection.UpdateOne(ctx, bson.M{
+		"_id":    id,
+		"status": from,
+	}, bson.M{
+		"$set": bson.M{"status": to, "updated_at": time.Now()},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *MongoUploadSessionStore) ListExpiredUploadSessions(ctx context.Context, now time.Time) ([]model.UploadSession, error) {
+	cursor, err := s.collection.Find(ctx, bson.M{
+		"status":     model.UploadSessionUploading,
+		"expires_at": bson.M{"$lte": now},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []model.UploadSession
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
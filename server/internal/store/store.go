@@ -0,0 +1,125 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/pateldivyesh1323/futflare/server/internal/model"
+)
+
+var ErrNotFound = errors.New("capsule not found")
+
+// ListFilter narrows the capsules returned by CapsuleStore.List to the ones
+// a user created or participates in, drafts included.
+type ListFilter struct {
+	UserID string
+	Email  string
+	Search string
+	Oldest bool
+	Skip   int64
+	Limit  int64
+}
+
+// CapsuleStore is the persistence layer used by the handlers and the scheduler.
+type CapsuleStore interface {
+	Create(ctx context.Context, c *model.Capsule) error
+	GetForUser(ctx context.Context, id primitive.ObjectID, userID, email string) (*model.Capsule, error)
+	// GetForContributor is GetForUser for requests that add to or show a
+	// draft, it also returns drafts to their participants.
+	GetForContributor(ctx context.Context, id primitive.ObjectID, userID, email string) (*model.Capsule, error)
+	List(ctx context.Context, filter ListFilter) ([]model.Capsule, int64, error)
+	// Update replaces the editable fields of a draft or sealed capsule. The
+	// quorum approvals are kept, except those of removed participants.
+	Update(ctx context.Context, c *model.Capsule) error
+	// AddContentItems appends items to a draft capsule. Items contributed by a
+	// participant also count against maxContributions of that participant. It
+	// returns ErrNotFound when the capsule is not a draft or would end up with
+	// more than maxItems, or the participant with more than maxContributions.
+	AddContentItems(ctx context.Context, id primitive.ObjectID, items []model.ContentItem, maxItems, maxContributions int) error
+	// UpdateStatus moves a capsule from one status to another. It returns
+	// ErrNotFound when the capsule is no longer in the from status.
+	UpdateStatus(ctx context.Context, id primitive.ObjectID, from, to model.CapsuleStatus) error
+	Delete(ctx context.Context, id primitive.ObjectID, creator string) error
+	// OpenDue opens every sealed capsule that is ready to open at now, see
+	// model.Capsule.ReadyToOpen.
+	OpenDue(ctx context.Context, now time.Time) (int64, error)
+	// SetApproval records or revokes a participant's key on a sealed quorum
+	// capsule. It returns ErrNotFound when the capsule is not sealed or has
+	// no quorum.
+	SetApproval(ctx context.Context, id primitive.ObjectID, email string, approved bool) error
+	// ListPendingRecurrences returns opened recurring capsules that have not
+	// spawned their next instance yet.
+	ListPendingRecurrences(ctx context.Context) ([]model.Capsule, error)
+	// LinkNextInstance marks next as the instance following capsule id and
+	// creates it. The mark is removed again when next cannot be created. A
+	// nil next ends the series. It returns ErrNotFound when the capsule
+	// already has a next instance.
+	LinkNextInstance(ctx context.Context, id primitive.ObjectID, next *model.Capsule) error
+	// ListSeries returns the instances of a recurring series visible to the
+	// user, ordered by open date.
+	ListSeries(ctx context.Context, seriesID primitive.ObjectID, userID, email string) ([]model.Capsule, error)
+	// SetDeadMansSwitch stores the switch state of a draft or sealed capsule,
+	// e.g. after its creator checks in.
+	SetDeadMansSwitch(ctx context.Context, id primitive.ObjectID, sw *model.DeadMansSwitch) error
+	// ListDueReminders returns sealed dead man's switch capsules whose creator
+	// should be reminded to check in.
+	ListDueReminders(ctx context.Context, now time.Time) ([]model.Capsule, error)
+	// MarkReminded records that the reminder scheduled at remindAt was sent.
+	MarkReminded(ctx context.Context, id primitive.ObjectID, remindAt time.Time) error
+	// AddUnlockedBy records that the user answered the passphrase of a locked
+	// capsule.
+	AddUnlockedBy(ctx context.Context, id primitive.ObjectID, userID string) error
+	// RecordLocationUnlock audits the first time a user opens a geofenced
+	// capsule from inside its fence. Later unlocks by the same user are
+	// ignored.
+	RecordLocationUnlock(ctx context.Context, id primitive.ObjectID, unlock model.LocationUnlock) error
+	// SetPredictionAnswer records or replaces a member's answer to a
+	// prediction of a draft or sealed capsule. It returns ErrNotFound when the
+	// capsule has opened or has no such prediction.
+	SetPredictionAnswer(ctx context.Context, id, predictionID primitive.ObjectID, answer model.PredictionAnswer) error
+	// SetPredictionOutcome marks whether the answer of email to a prediction
+	// of an opened capsule came true. It returns ErrNotFound when the capsule
+	// is not opened or the answer does not exist.
+	SetPredictionOutcome(ctx context.Context, id, predictionID primitive.ObjectID, email string, cameTrue bool) error
+	// SetGoalAssessment records or replaces a member's assessment of a goal of
+	// an opened capsule. It returns ErrNotFound when the capsule is not opened
+	// or has no such goal.
+	SetGoalAssessment(ctx context.Context, id, goalID primitive.ObjectID, assessment model.GoalAssessment) error
+	// SetLinkPreviews stores the previews of the links of a capsule, keyed by
+	// URL. Links that already have a preview keep it.
+	SetLinkPreviews(ctx context.Context, id primitive.ObjectID, previews map[string]*model.LinkPreview) error
+	// ListUploads returns the uploads referenced by the content items of all
+	// capsules.
+	ListUploads(ctx context.Context) ([]model.Upload, error)
+	// UploadReferenced reports whether any capsule refers to the upload by
+	// its object key or, for items created before uploads were private, by
+	// its URL.
+	UploadReferenced(ctx context.Context, upload model.Upload) (bool, error)
+	// AdvanceReveals moves the next reveal time of opened capsules whose
+	// next item reveal has passed. It returns the number of capsules updated.
+	AdvanceReveals(ctx context.Context, now time.Time) (int64, error)
+}
+
+// UploadSessionStore keeps track of multipart uploads.
+type UploadSessionStore interface {
+	CreateUploadSession(ctx context.Context, s *model.UploadSession) error
+	// GetUploadSession returns an upload session started by the user.
+	GetUploadSession(ctx context.Context, id primitive.ObjectID, userID string) (*model.UploadSession, error)
+	// SetUploadParts records the parts uploaded so far. It returns ErrNotFound
+	// when the session is no longer uploading.
+	SetUploadParts(ctx context.Context, id primitive.ObjectID, parts []model.UploadPart) error
+	// SetUploadProgress records the parts and the size of the pending tail of
+	// a tus upload that had prevParts parts and a tail of prevTailSize bytes.
+	// It returns ErrNotFound when the session is no longer uploading or
+	// another request recorded progress first.
+	SetUploadProgress(ctx context.Context, id primitive.ObjectID, prevParts int, prevTailSize int64, parts []model.UploadPart, tailSize int64) error
+	// UpdateUploadStatus moves a session from one status to another. It
+	// returns ErrNotFound when the session is no longer in the from status.
+	UpdateUploadStatus(ctx context.Context, id primitive.ObjectID, from, to model.UploadSessionStatus) error
+	// ListExpiredUploadSessions returns sessions still uploading after they
+	// expired.
+	ListExpiredUploadSessions(ctx context.Context, now time.Time) ([]model.UploadSession, error)
+}
@@ -0,0 +1,525 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/pateldivyesh1323/futflare/server/internal/model"
+)
+
+// The tests of this file run against every store. The MongoDB stores are
+// only tested when FUTFLARE_TEST_MONGODB_URL is set, each test uses its own
+// database that is dropped afterwards.
+const TEST_MONGODB_URL_ENV = "FUTFLARE_TEST_MONGODB_URL"
+
+type stores struct {
+	capsules CapsuleStore
+	uploads  UploadSessionStore
+}
+
+func forEachStore(t *testing.T, test func(t *testing.T, s stores)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, stores{capsules: NewMemoryCapsuleStore(), uploads: NewMemoryUploadSessionStore()})
+	})
+
+	t.Run("mongo", func(t *testing.T) {
+		url := os.Getenv(TEST_MONGODB_URL_ENV)
+		if url == "" {
+			t.Skipf("%s is not set", TEST_MONGODB_URL_ENV)
+		}
+
+		ctx := context.Background()
+		client, err := mongo.Connect(ctx, options.Client().ApplyURI(url))
+		if err != nil {
+			t.Fatal(err)
+		}
+		db := client.Database(fmt.Sprintf("futflare_test_%s", primitive.NewObjectID().Hex()))
+		t.Cleanup(func() {
+			db.Drop(ctx)
+			client.Disconnect(ctx)
+		})
+
+		capsules := NewMongoCapsuleStore(db)
+		if err := capsules.EnsureIndexes(ctx); err != nil {
+			t.Fatal(err)
+		}
+		test(t, stores{capsules: capsules, uploads: NewMongoUploadSessionStore(db)})
+	})
+}
+
+func newTestCapsule(status model.CapsuleStatus, participants ...string) *model.Capsule {
+	return &model.Capsule{
+		ID:                primitive.NewObjectID(),
+		Title:             "Test capsule",
+		Description:       "A capsule for the tests",
+		Creator:           "creator",
+		Status:            status,
+		ParticipantEmails: participants,
+		ScheduledOpenDate: time.Now().Add(time.Hour).Truncate(time.Millisecond),
+		ContentItems:      []model.ContentItem{testMessage("Hello")},
+		CreatedAt:         time.Now().Truncate(time.Millisecond),
+	}
+}
+
+func testMessage(text string) model.ContentItem {
+	return model.ContentItem{Type: model.ContentTypeMessage, Content: &model.MessageContent{Text: text}}
+}
+
+func mustCreate(t *testing.T, s CapsuleStore, c *model.Capsule) {
+	t.Helper()
+	if err := s.Create(context.Background(), c); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func mustGet(t *testing.T, s CapsuleStore, id primitive.ObjectID) *model.Capsule {
+	t.Helper()
+	c, err := s.GetForUser(context.Background(), id, "creator", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestCapsuleLifecycle(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s stores) {
+		ctx := context.Background()
+		c := newTestCapsule(model.CapsuleStatusSealed, "p@example.com")
+		mustCreate(t, s.capsules, c)
+
+		if _, err := s.capsules.GetForUser(ctx, c.ID, "participant", "p@example.com"); err != nil {
+			t.Errorf("participant: GetForUser = %v", err)
+		}
+		if _, err := s.capsules.GetForUser(ctx, c.ID, "stranger", "s@example.com"); err != ErrNotFound {
+			t.Errorf("stranger: GetForUser = %v, want ErrNotFound", err)
+		}
+
+		c.Title = "Renamed"
+		if err := s.capsules.Update(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+		capsules, total, err := s.capsules.List(ctx, ListFilter{UserID: "creator", Search: "renam", Limit: 10})
+		if err != nil || total != 1 || len(capsules) != 1 || capsules[0].Title != "Renamed" {
+			t.Errorf("List = %v, %d, %v, want the renamed capsule", capsules, total, err)
+		}
+
+		if err := s.capsules.Delete(ctx, c.ID, "participant"); err != ErrNotFound {
+			t.Errorf("delete by participant = %v, want ErrNotFound", err)
+		}
+		if err := s.capsules.Delete(ctx, c.ID, "creator"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.capsules.GetForUser(ctx, c.ID, "creator", ""); err != ErrNotFound {
+			t.Errorf("GetForUser after delete = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestOpenDueAfterUpdate(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s stores) {
+		ctx := context.Background()
+		c := newTestCapsule(model.CapsuleStatusSealed)
+		mustCreate(t, s.capsules, c)
+
+		c.Title = "Renamed"
+		c.ScheduledOpenDate = time.Now().Add(-time.Minute).Truncate(time.Millisecond)
+		if err := s.capsules.Update(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+
+		opened, err := s.capsules.OpenDue(ctx, time.Now())
+		if err != nil || opened != 1 {
+			t.Fatalf("OpenDue = %d, %v, want 1", opened, err)
+		}
+		if got := mustGet(t, s.capsules, c.ID); got.Status != model.CapsuleStatusOpened || got.Title != "Renamed" {
+			t.Errorf("capsule = %q %s, want Renamed opened", got.Title, got.Status)
+		}
+	})
+}
+
+func TestUpdateKeepsApprovals(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s stores) {
+		ctx := context.Background()
+		c := newTestCapsule(model.CapsuleStatusSealed, "a@example.com", "b@example.com")
+		c.Quorum = &model.Quorum{Required: 2}
+		mustCreate(t, s.capsules, c)
+
+		for _, email := range []string{"a@example.com", "b@example.com"} {
+			if err := s.capsules.SetApproval(ctx, c.ID, email, true); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// The edit comes from a request, it carries no approvals.
+		c.ParticipantEmails = []string{"a@example.com", "c@example.com"}
+		c.Quorum = &model.Quorum{Required: 1}
+		if err := s.capsules.Update(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+
+		got := mustGet(t, s.capsules, c.ID)
+		if got.Quorum == nil || got.Quorum.Required != 1 {
+			t.Fatalf("quorum = %+v, want 1 required", got.Quorum)
+		}
+		if len(got.Quorum.Approvals) != 1 || !got.Quorum.HasApproved("a@example.com") {
+			t.Errorf("approvals = %v, want [a@example.com]", got.Quorum.Approvals)
+		}
+
+		c.Quorum = nil
+		if err := s.capsules.Update(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+		if got := mustGet(t, s.capsules, c.ID); got.Quorum != nil {
+			t.Errorf("quorum = %+v after removing it", got.Quorum)
+		}
+	})
+}
+
+func TestDraftVisibility(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s stores) {
+		ctx := context.Background()
+		draft := newTestCapsule(model.CapsuleStatusDraft, "p@example.com")
+		mustCreate(t, s.capsules, draft)
+
+		if _, err := s.capsules.GetForUser(ctx, draft.ID, "participant", "p@example.com"); err != ErrNotFound {
+			t.Errorf("participant: GetForUser = %v, want ErrNotFound", err)
+		}
+		if _, err := s.capsules.GetForContributor(ctx, draft.ID, "participant", "p@example.com"); err != nil {
+			t.Errorf("participant: GetForContributor = %v", err)
+		}
+		if _, err := s.capsules.GetForContributor(ctx, draft.ID, "stranger", "s@example.com"); err != ErrNotFound {
+			t.Errorf("stranger: GetForContributor = %v, want ErrNotFound", err)
+		}
+
+		if _, total, err := s.capsules.List(ctx, ListFilter{UserID: "participant", Email: "p@example.com", Limit: 10}); err != nil || total != 1 {
+			t.Errorf("participant: List = %d capsules, %v, want the draft", total, err)
+		}
+		if _, total, err := s.capsules.List(ctx, ListFilter{UserID: "stranger", Email: "s@example.com", Limit: 10}); err != nil || total != 0 {
+			t.Errorf("stranger: List = %d capsules, %v, want none", total, err)
+		}
+	})
+}
+
+func TestLinkNextInstance(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s stores) {
+		ctx := context.Background()
+		c := newTestCapsule(model.CapsuleStatusOpened)
+		c.Recurrence = &model.Recurrence{Frequency: model.RecurrenceYearly}
+		c.SeriesID = &c.ID
+		mustCreate(t, s.capsules, c)
+
+		pending, err := s.capsules.ListPendingRecurrences(ctx)
+		if err != nil || len(pending) != 1 {
+			t.Fatalf("ListPendingRecurrences = %d capsules, %v, want 1", len(pending), err)
+		}
+
+		next := newTestCapsule(model.CapsuleStatusDraft)
+		next.SeriesID = &c.ID
+		next.ScheduledOpenDate = c.ScheduledOpenDate.AddDate(1, 0, 0)
+		if err := s.capsules.LinkNextInstance(ctx, c.ID, next); err != nil {
+			t.Fatal(err)
+		}
+
+		// A second scheduler run must not spawn the instance again.
+		again := newTestCapsule(model.CapsuleStatusDraft)
+		if err := s.capsules.LinkNextInstance(ctx, c.ID, again); err != ErrNotFound {
+			t.Errorf("second LinkNextInstance = %v, want ErrNotFound", err)
+		}
+
+		series, err := s.capsules.ListSeries(ctx, c.ID, "creator", "")
+		if err != nil || len(series) != 2 || series[1].ID != next.ID {
+			t.Errorf("ListSeries = %d capsules, %v, want the capsule and its next instance", len(series), err)
+		}
+		if pending, _ := s.capsules.ListPendingRecurrences(ctx); len(pending) != 0 {
+			t.Errorf("ListPendingRecurrences = %d capsules after linking, want none", len(pending))
+		}
+
+		ended := newTestCapsule(model.CapsuleStatusOpened)
+		ended.Recurrence = &model.Recurrence{Frequency: model.RecurrenceYearly}
+		mustCreate(t, s.capsules, ended)
+		if err := s.capsules.LinkNextInstance(ctx, ended.ID, nil); err != nil {
+			t.Fatal(err)
+		}
+		if pending, _ := s.capsules.ListPendingRecurrences(ctx); len(pending) != 0 {
+			t.Errorf("ListPendingRecurrences = %d capsules after ending a series, want none", len(pending))
+		}
+	})
+}
+
+func TestCheckInReminders(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s stores) {
+		ctx := context.Background()
+		now := time.Now().Truncate(time.Millisecond)
+		c := newTestCapsule(model.CapsuleStatusSealed)
+		c.DeadMansSwitch = &model.DeadMansSwitch{CheckInDays: 7}
+		c.DeadMansSwitch.CheckIn(now.AddDate(0, 0, -6), 48*time.Hour)
+		mustCreate(t, s.capsules, c)
+
+		due, err := s.capsules.ListDueReminders(ctx, now)
+		if err != nil || len(due) != 1 {
+			t.Fatalf("ListDueReminders = %d capsules, %v, want 1", len(due), err)
+		}
+
+		// The creator checked in after the reminder was listed, marking the
+		// old reminder must not silence the next one.
+		sw := *c.DeadMansSwitch
+		sw.CheckIn(now.AddDate(0, 0, -6).Add(time.Minute), 48*time.Hour)
+		if err := s.capsules.SetDeadMansSwitch(ctx, c.ID, &sw); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.capsules.MarkReminded(ctx, c.ID, due[0].DeadMansSwitch.RemindAt); err != nil {
+			t.Fatal(err)
+		}
+		if due, _ := s.capsules.ListDueReminders(ctx, now); len(due) != 1 {
+			t.Errorf("ListDueReminders = %d capsules after a stale mark, want 1", len(due))
+		}
+
+		if err := s.capsules.MarkReminded(ctx, c.ID, sw.RemindAt); err != nil {
+			t.Fatal(err)
+		}
+		if due, _ := s.capsules.ListDueReminders(ctx, now); len(due) != 0 {
+			t.Errorf("ListDueReminders = %d capsules after marking, want none", len(due))
+		}
+	})
+}
+
+func TestAddContentItemsLimits(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s stores) {
+		ctx := context.Background()
+		c := newTestCapsule(model.CapsuleStatusDraft, "p@example.com")
+		mustCreate(t, s.capsules, c)
+
+		contribution := func(text string) []model.ContentItem {
+			item := testMessage(text)
+			item.ContributedBy = "p@example.com"
+			return []model.ContentItem{item}
+		}
+
+		if err := s.capsules.AddContentItems(ctx, c.ID, contribution("First"), 10, 2); err != nil {
+			t.Fatalf("first contribution: %v", err)
+		}
+		if err := s.capsules.AddContentItems(ctx, c.ID, contribution("Second"), 10, 2); err != nil {
+			t.Fatalf("second contribution: %v", err)
+		}
+		if err := s.capsules.AddContentItems(ctx, c.ID, contribution("Third"), 10, 2); err != ErrNotFound {
+			t.Errorf("contribution past the participant limit = %v, want ErrNotFound", err)
+		}
+
+		// The creator's items only count against the capsule limit.
+		if err := s.capsules.AddContentItems(ctx, c.ID, []model.ContentItem{testMessage("Creator")}, 4, 2); err != nil {
+			t.Fatalf("creator item: %v", err)
+		}
+		if err := s.capsules.AddContentItems(ctx, c.ID, []model.ContentItem{testMessage("Late")}, 4, 2); err != ErrNotFound {
+			t.Errorf("item past the capsule limit = %v, want ErrNotFound", err)
+		}
+		if got := mustGet(t, s.capsules, c.ID); len(got.ContentItems) != 4 {
+			t.Errorf("capsule has %d items, want 4", len(got.ContentItems))
+		}
+
+		sealed := newTestCapsule(model.CapsuleStatusSealed)
+		mustCreate(t, s.capsules, sealed)
+		if err := s.capsules.AddContentItems(ctx, sealed.ID, []model.ContentItem{testMessage("Late")}, 10, 2); err != ErrNotFound {
+			t.Errorf("adding to a sealed capsule = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestSetPredictionAnswer(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s stores) {
+		ctx := context.Background()
+		predictionID := primitive.NewObjectID()
+		c := newTestCapsule(model.CapsuleStatusSealed, "p@example.com")
+		c.ContentItems = append(c.ContentItems, model.ContentItem{
+			Type:    model.ContentTypePrediction,
+			Content: &model.PredictionContent{ID: predictionID, Question: "Will it rain?", Answers: []model.PredictionAnswer{}},
+		})
+		mustCreate(t, s.capsules, c)
+
+		answer := model.PredictionAnswer{ParticipantEmail: "p@example.com", Answer: "Yes", AnsweredAt: time.Now().Truncate(time.Millisecond)}
+		if err := s.capsules.SetPredictionAnswer(ctx, c.ID, predictionID, answer); err != nil {
+			t.Fatalf("answer: %v", err)
+		}
+		answer.Answer = "No"
+		if err := s.capsules.SetPredictionAnswer(ctx, c.ID, predictionID, answer); err != nil {
+			t.Fatalf("changed answer: %v", err)
+		}
+
+		_, prediction := model.FindPrediction(mustGet(t, s.capsules, c.ID).ContentItems, predictionID)
+		if len(prediction.Answers) != 1 || prediction.Answers[0].Answer != "No" {
+			t.Errorf("answers = %+v, want the changed answer", prediction.Answers)
+		}
+
+		if err := s.capsules.SetPredictionAnswer(ctx, c.ID, primitive.NewObjectID(), answer); err != ErrNotFound {
+			t.Errorf("answer to an unknown prediction = %v, want ErrNotFound", err)
+		}
+		if err := s.capsules.UpdateStatus(ctx, c.ID, model.CapsuleStatusSealed, model.CapsuleStatusOpened); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.capsules.SetPredictionAnswer(ctx, c.ID, predictionID, answer); err != ErrNotFound {
+			t.Errorf("answer after opening = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestSetLinkPreviews(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s stores) {
+		ctx := context.Background()
+		kept := &model.LinkPreview{Title: "Kept", CapturedAt: time.Now().Truncate(time.Millisecond)}
+		c := newTestCapsule(model.CapsuleStatusSealed)
+		c.ContentItems = []model.ContentItem{
+			{Type: model.ContentTypeLink, Content: &model.LinkContent{URL: "https://example.com/a"}},
+			{Type: model.ContentTypeLink, Content: &model.LinkContent{URL: "https://example.com/b", Preview: kept}},
+			{Type: model.ContentTypeLink, Content: &model.LinkContent{URL: "https://example.com/c"}},
+		}
+		mustCreate(t, s.capsules, c)
+
+		err := s.capsules.SetLinkPreviews(ctx, c.ID, map[string]*model.LinkPreview{
+			"https://example.com/a": {Title: "A", CapturedAt: time.Now().Truncate(time.Millisecond)},
+			"https://example.com/b": {Title: "Replaced", CapturedAt: time.Now().Truncate(time.Millisecond)},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []string{"A", "Kept", ""}
+		for i, item := range mustGet(t, s.capsules, c.ID).ContentItems {
+			title := ""
+			if p := item.Content.(*model.LinkContent).Preview; p != nil {
+				title = p.Title
+			}
+			if title != want[i] {
+				t.Errorf("link %d has preview %q, want %q", i, title, want[i])
+			}
+		}
+	})
+}
+
+func TestUploads(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s stores) {
+		ctx := context.Background()
+		c := newTestCapsule(model.CapsuleStatusSealed)
+		c.ContentItems = append(c.ContentItems,
+			model.ContentItem{Type: model.ContentTypeImage, Content: &model.ImageContent{Upload: model.Upload{ObjectKey: "uploads/new.png"}}},
+			model.ContentItem{Type: model.ContentTypeImage, Content: &model.ImageContent{Upload: model.Upload{URL: "https://cdn.example.com/old.png"}}},
+			model.ContentItem{Type: model.ContentTypeLink, Content: &model.LinkContent{URL: "https://cdn.example.com/linked.png"}},
+		)
+		mustCreate(t, s.capsules, c)
+
+		uploads, err := s.capsules.ListUploads(ctx)
+		if err != nil || len(uploads) != 2 {
+			t.Fatalf("ListUploads = %v, %v, want 2 uploads", uploads, err)
+		}
+
+		tests := []struct {
+			upload model.Upload
+			want   bool
+		}{
+			{model.Upload{ObjectKey: "uploads/new.png"}, true},
+			{model.Upload{URL: "https://cdn.example.com/old.png"}, true},
+			{model.Upload{ObjectKey: "uploads/other.png"}, false},
+			// A link to an object does not keep it.
+			{model.Upload{URL: "https://cdn.example.com/linked.png"}, false},
+		}
+		for _, tt := range tests {
+			if got, err := s.capsules.UploadReferenced(ctx, tt.upload); err != nil || got != tt.want {
+				t.Errorf("UploadReferenced(%+v) = %v, %v, want %v", tt.upload, got, err, tt.want)
+			}
+		}
+	})
+}
+
+func newTestUploadSession(expiresAt time.Time) *model.UploadSession {
+	now := time.Now().Truncate(time.Millisecond)
+	return &model.UploadSession{
+		ID:          primitive.NewObjectID(),
+		UserID:      "creator",
+		UploadID:    "upload",
+		ObjectKey:   "uploads/creator/clip.mp4",
+		ContentType: model.ContentTypeVideo,
+		FileSize:    100,
+		PartSize:    100,
+		PartCount:   1,
+		Parts:       []model.UploadPart{},
+		Status:      model.UploadSessionUploading,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		ExpiresAt:   expiresAt.Truncate(time.Millisecond),
+	}
+}
+
+func TestUploadSessions(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s stores) {
+		ctx := context.Background()
+		session := newTestUploadSession(time.Now().Add(time.Hour))
+		expired := newTestUploadSession(time.Now().Add(-time.Hour))
+		for _, u := range []*model.UploadSession{session, expired} {
+			if err := s.uploads.CreateUploadSession(ctx, u); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if _, err := s.uploads.GetUploadSession(ctx, session.ID, "other"); err != ErrNotFound {
+			t.Errorf("GetUploadSession of another user = %v, want ErrNotFound", err)
+		}
+
+		parts := []model.UploadPart{{Number: 1, ETag: "etag", Size: 100}}
+		if err := s.uploads.SetUploadParts(ctx, session.ID, parts); err != nil {
+			t.Fatal(err)
+		}
+		got, err := s.uploads.GetUploadSession(ctx, session.ID, "creator")
+		if err != nil || len(got.Parts) != 1 || got.Parts[0].ETag != "etag" {
+			t.Fatalf("GetUploadSession = %+v, %v, want the recorded part", got, err)
+		}
+
+		listed, err := s.uploads.ListExpiredUploadSessions(ctx, time.Now())
+		if err != nil || len(listed) != 1 || listed[0].ID != expired.ID {
+			t.Errorf("ListExpiredUploadSessions = %d sessions, %v, want the expired one", len(listed), err)
+		}
+
+		if err := s.uploads.UpdateUploadStatus(ctx, session.ID, model.UploadSessionUploading, model.UploadSessionCompleted); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.uploads.UpdateUploadStatus(ctx, session.ID, model.UploadSessionUploading, model.UploadSessionAborted); err != ErrNotFound {
+			t.Errorf("aborting a completed upload = %v, want ErrNotFound", err)
+		}
+		if err := s.uploads.SetUploadParts(ctx, session.ID, parts); err != ErrNotFound {
+			t.Errorf("SetUploadParts after completing = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestSetUploadProgress(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s stores) {
+		ctx := context.Background()
+		session := newTestUploadSession(time.Now().Add(time.Hour))
+		session.Tus = true
+		if err := s.uploads.CreateUploadSession(ctx, session); err != nil {
+			t.Fatal(err)
+		}
+
+		parts := []model.UploadPart{{Number: 1, ETag: "etag", Size: 100}}
+		if err := s.uploads.SetUploadProgress(ctx, session.ID, 0, 0, nil, 40); err != nil {
+			t.Fatal(err)
+		}
+		// A request that loaded the session before the first one recorded
+		// its progress loses.
+		if err := s.uploads.SetUploadProgress(ctx, session.ID, 0, 0, nil, 60); err != ErrNotFound {
+			t.Errorf("progress from a stale session = %v, want ErrNotFound", err)
+		}
+		if err := s.uploads.SetUploadProgress(ctx, session.ID, 0, 40, parts, 0); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := s.uploads.GetUploadSession(ctx, session.ID, "creator")
+		if err != nil || len(got.Parts) != 1 || got.TailSize != 0 || got.Offset() != 100 {
+			t.Errorf("GetUploadSession = %+v, %v, want the part without a tail", got, err)
+		}
+	})
+}